@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// flagSpec describes one --flag a command accepts
+type flagSpec struct {
+	name  string // without the leading --
+	value string // placeholder shown in help, e.g. "<date>"; empty for on/off flags
+	usage string
+}
+
+// command describes a REPL command. The same description drives argument
+// parsing, usage errors and the help output, so they can't disagree.
+type command struct {
+	name     string
+	aliases  []string
+	args     string // e.g. "<id> [duration]"; "..." marks an argument taking the rest of the line
+	summary  string
+	flags    []flagSpec
+	examples []string
+	readOnly bool // doesn't change any todos, so nothing needs saving afterwards
+	run      func(a *app, inv *invocation) error
+}
+
+// invocation is a parsed command line
+type invocation struct {
+	args   []token
+	values map[string][]string // flag name -> every value given, in order
+}
+
+// arg returns the text of the i-th argument, or "" if there are fewer
+func (inv *invocation) arg(i int) string {
+	if i >= len(inv.args) {
+		return ""
+	}
+
+	return inv.args[i].text
+}
+
+// rest returns the text of the arguments from the i-th on
+func (inv *invocation) rest(i int) []string {
+	var words []string
+
+	for _, t := range inv.args[min(i, len(inv.args)):] {
+		words = append(words, t.text)
+	}
+
+	return words
+}
+
+// flag returns the last value given for a flag, or "" if it wasn't given
+func (inv *invocation) flag(name string) string {
+	values := inv.values[name]
+
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[len(values)-1]
+}
+
+// has reports whether a flag was given
+func (inv *invocation) has(name string) bool {
+	_, ok := inv.values[name]
+	return ok
+}
+
+// usage returns the synopsis of the command, e.g. "cal [--week] [month]"
+func (c *command) usage() string {
+	parts := []string{c.name}
+
+	for _, f := range c.flags {
+		if f.value == "" {
+			parts = append(parts, fmt.Sprintf("[--%s]", f.name))
+		} else {
+			parts = append(parts, fmt.Sprintf("[--%s %s]", f.name, f.value))
+		}
+	}
+
+	if c.args != "" {
+		parts = append(parts, c.args)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// arity returns how many arguments the command needs and allows; max is -1
+// when the last argument takes the rest of the line
+func (c *command) arity() (minArgs, maxArgs int) {
+	for _, arg := range strings.Fields(c.args) {
+		if strings.Contains(arg, "...") {
+			if strings.HasPrefix(arg, "<") {
+				minArgs++
+			}
+
+			return minArgs, -1
+		}
+
+		if strings.HasPrefix(arg, "<") {
+			minArgs++
+		}
+
+		maxArgs++
+	}
+
+	return minArgs, maxArgs
+}
+
+func (c *command) lookupFlag(name string) (flagSpec, bool) {
+	for _, f := range c.flags {
+		if f.name == name {
+			return f, true
+		}
+	}
+
+	return flagSpec{}, false
+}
+
+// parse splits the words after the command name into flags and arguments.
+// Flags may come anywhere, as --name value or --name=value; a quoted word
+// or anything after a bare -- is always an argument, taken literally.
+func (c *command) parse(words []token) (*invocation, error) {
+	inv := &invocation{values: make(map[string][]string)}
+	flagsDone := false
+
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+
+		if flagsDone || word.quoted || !strings.HasPrefix(word.text, "--") {
+			word.quoted = word.quoted || flagsDone
+			inv.args = append(inv.args, word)
+			continue
+		}
+
+		if word.text == "--" {
+			flagsDone = true
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(word.text, "--"), "=")
+		spec, ok := c.lookupFlag(name)
+
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("unknown flag --%s", name)
+		case spec.value == "" && hasValue:
+			return nil, fmt.Errorf("flag --%s doesn't take a value", name)
+		case spec.value != "" && !hasValue:
+			if i+1 == len(words) {
+				return nil, fmt.Errorf("flag --%s needs a value %s", name, spec.value)
+			}
+
+			i++
+			value = words[i].text
+		}
+
+		inv.values[name] = append(inv.values[name], value)
+	}
+
+	minArgs, maxArgs := c.arity()
+
+	if len(inv.args) < minArgs {
+		return nil, fmt.Errorf("missing arguments")
+	}
+
+	if maxArgs >= 0 && len(inv.args) > maxArgs {
+		return nil, fmt.Errorf("too many arguments")
+	}
+
+	return inv, nil
+}
+
+// findCommand looks a command up by name or alias
+func findCommand(commands []*command, name string) (*command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+
+		for _, alias := range c.aliases {
+			if alias == name {
+				return c, true
+			}
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,924 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/neel07sanghvi/todo-cli/internal/dates"
+	"github.com/neel07sanghvi/todo-cli/internal/render"
+	"github.com/neel07sanghvi/todo-cli/internal/todo"
+)
+
+// errQuit is returned by the exit command to end the REPL
+var errQuit = errors.New("quit")
+
+// attributeFlags are the flags add and update accept as an alternative to
+// writing attributes inline
+var attributeFlags = []flagSpec{
+	{name: "due", value: "<date>", usage: "Set the due date (same as due:<date>)"},
+	{name: "priority", value: "<H|M|L>", usage: "Set the priority (same as pri:<H|M|L>)"},
+	{name: "tag", value: "<tag>", usage: "Add a tag; may be repeated (same as +tag)"},
+	{name: "depends", value: "<ids>", usage: "Set the todos this one waits for (same as depends:<ids>)"},
+}
+
+// newCommands returns every REPL command in the order help lists them
+func newCommands() []*command {
+	return []*command{
+		{
+			name:     "add",
+			args:     "<task...>",
+			summary:  "Add a new todo item",
+			flags:    attributeFlags,
+			examples: []string{"add Buy groceries", "add Pay rent due:friday pri:H +home", `add --due friday --priority H "Fix #12"`},
+			run:      (*app).add,
+		},
+		{
+			name:    "list",
+			args:    "[filter...]",
+			summary: "List todo items, optionally only those matching due:<date>, pri:<H|M|L>, +tag or -tag",
+			flags: []flagSpec{
+				{name: "all", usage: "List todos from every list, grouped by list, including snoozed and waiting ones"},
+				{name: "format", value: "<text|table|json|ndjson>", usage: "Choose the output format"},
+				{name: "sort", value: "<order>", usage: "Sort by id, created, task, status, due or urgency"},
+				{name: "template", value: "<tmpl>", usage: "Print each todo with a Go text/template"},
+			},
+			examples: []string{"list", "list --all --format table", "list --sort urgency due:eow", "list --template '{{.ID}} {{.Task}}'"},
+			readOnly: true,
+			run:      (*app).list,
+		},
+		{
+			name:     "next",
+			summary:  "Show the most urgent todo that isn't blocked",
+			readOnly: true,
+			run:      (*app).next,
+		},
+		{
+			name:     "explain",
+			args:     "<id>",
+			summary:  "Show how the urgency of a todo is computed",
+			readOnly: true,
+			run:      (*app).explain,
+		},
+		{
+			name:     "update",
+			args:     "<id> [task...]",
+			summary:  "Update the task and attributes of a todo item",
+			flags:    attributeFlags,
+			examples: []string{"update 1 Buy groceries and cook dinner", "update 1 --due= -home", `update 1 "pri:H is the task text, not an attribute"`},
+			run:      (*app).update,
+		},
+		{
+			name:     "delete",
+			args:     "<id>",
+			summary:  "Delete a todo item",
+			examples: []string{"delete 1"},
+			run:      (*app).delete,
+		},
+		{
+			name:     "complete",
+			args:     "<id>",
+			summary:  "Mark a todo item as completed",
+			examples: []string{"complete 1", "complete 3f2a"},
+			run:      (*app).complete,
+		},
+		{
+			name:     "incomplete",
+			args:     "<id>",
+			summary:  "Mark a todo item as incomplete",
+			examples: []string{"incomplete 1"},
+			run:      (*app).incomplete,
+		},
+		{
+			name:     "snooze",
+			args:     "<id> <until:date>",
+			summary:  "Hide a todo from list until a later day",
+			examples: []string{"snooze 3 until:monday", "snooze 3 +2w"},
+			run:      (*app).snooze,
+		},
+		{
+			name:     "wait",
+			aliases:  []string{"waiting"},
+			args:     "<id> [until:date] [for...]",
+			summary:  "Mark a todo as waiting on someone else, hidden from list until the follow-up date (a week by default)",
+			examples: []string{"wait 4 Alice", "wait 4 until:friday the landlord to call back"},
+			run:      (*app).wait,
+		},
+		{
+			name:     "resume",
+			aliases:  []string{"unsnooze"},
+			args:     "<id>",
+			summary:  "Bring a snoozed or waiting todo back right away",
+			examples: []string{"resume 3"},
+			run:      (*app).resume,
+		},
+		{
+			name:    "cal",
+			args:    "[month]",
+			summary: "Show a month calendar marking due and completed todos",
+			flags: []flagSpec{
+				{name: "week", usage: "Show the todos of the week containing the given date, day by day"},
+			},
+			examples: []string{"cal", "cal next", "cal 2024-03", "cal --week friday"},
+			readOnly: true,
+			run:      (*app).cal,
+		},
+		{
+			name:     "focus",
+			args:     "<id> [focus] [break]",
+			summary:  "Run pomodoro focus sessions with breaks on a todo (default 25m and 5m)",
+			examples: []string{"focus 1", "focus 1 50m 10m"},
+			run:      (*app).focus,
+		},
+		{
+			name:     "stats",
+			summary:  "Show pomodoros per todo and per day",
+			readOnly: true,
+			run:      (*app).stats,
+		},
+		{
+			name:     "annotate",
+			args:     "<id> <text...>",
+			summary:  "Append a timestamped annotation to a todo item",
+			examples: []string{"annotate 1 Called the store, they open at 9"},
+			run:      (*app).annotate,
+		},
+		{
+			name:    "note",
+			args:    "<id>",
+			summary: "Edit the Markdown note of a todo item in $EDITOR; it is written unencrypted to a private temporary file, removed afterwards",
+			run:     (*app).note,
+		},
+		{
+			name:     "show",
+			args:     "<id>",
+			summary:  "Show a todo item with its annotations and note",
+			readOnly: true,
+			run:      (*app).show,
+		},
+		{
+			name:    "search",
+			args:    "<text...>",
+			summary: "Find pending todos by task, tag, annotation or note text, tolerating typos",
+			flags: []flagSpec{
+				{name: "case-sensitive", usage: "Only match the exact case"},
+				{name: "regex", usage: "Search for a regular expression instead of words"},
+				{name: "include-completed", usage: "Search completed todos too"},
+			},
+			examples: []string{"search dentist", "search grocries", "search --regex 'fix #[0-9]+'", "search --include-completed invoice"},
+			readOnly: true,
+			run:      (*app).search,
+		},
+		{
+			name:     "use",
+			args:     "<list>",
+			summary:  "Switch to (or create) a named list",
+			examples: []string{"use work"},
+			run:      (*app).use,
+		},
+		{
+			name:     "lists",
+			summary:  "Show all lists, marking the current one",
+			readOnly: true,
+			run:      (*app).lists,
+		},
+		{
+			name:     "mv",
+			args:     "<id> <list>",
+			summary:  "Move a todo item to another list",
+			examples: []string{"mv 4 personal"},
+			run:      (*app).move,
+		},
+		{
+			name:    "encrypt",
+			summary: "Store the current list encrypted with a passphrase",
+			run:     (*app).encrypt,
+		},
+		{
+			name:    "rekey",
+			summary: "Change the passphrase of the current list",
+			run:     (*app).rekey,
+		},
+		{
+			name:    "decrypt",
+			summary: "Store the current list as plain JSON again",
+			run:     (*app).decrypt,
+		},
+		{
+			name:     "help",
+			args:     "[command]",
+			summary:  "Show this help message, or the flags and examples of one command",
+			examples: []string{"help", "help list"},
+			readOnly: true,
+			run:      (*app).help,
+		},
+		{
+			name:     "exit",
+			aliases:  []string{"quit"},
+			summary:  "Exit the application",
+			readOnly: true,
+			run: func(*app, *invocation) error {
+				return errQuit
+			},
+		},
+	}
+}
+
+// modification builds a change from the arguments from the i-th on and the
+// attribute flags. Quoted arguments are always task text, so a task can
+// start with a number or contain a colon. For a new todo (adding) -tag is
+// task text too, since there are no tags to remove.
+func modification(inv *invocation, from int, adding bool) (todo.Modification, error) {
+	now := time.Now()
+	var m todo.Modification
+	var task []string
+
+	for _, arg := range inv.args[min(from, len(inv.args)):] {
+		if !arg.quoted && !(adding && todo.RemovesTag(arg.text)) {
+			isAttribute, err := m.ParseWord(arg.text, now)
+
+			if err != nil {
+				return m, err
+			}
+
+			if isAttribute {
+				continue
+			}
+		}
+
+		task = append(task, arg.text)
+	}
+
+	m.Task = strings.Join(task, " ")
+
+	for _, due := range inv.values["due"] {
+		if _, err := m.ParseWord("due:"+due, now); err != nil {
+			return m, err
+		}
+	}
+
+	for _, priority := range inv.values["priority"] {
+		if _, err := m.ParseWord("pri:"+priority, now); err != nil {
+			return m, err
+		}
+	}
+
+	for _, tag := range inv.values["tag"] {
+		if isTag, _ := m.ParseWord("+"+tag, now); !isTag {
+			return m, fmt.Errorf("invalid tag %q (letters, digits, _ and -, starting with a letter)", tag)
+		}
+	}
+
+	for _, depends := range inv.values["depends"] {
+		if _, err := m.ParseWord("depends:"+depends, now); err != nil {
+			return m, err
+		}
+	}
+
+	return m, nil
+}
+
+func (a *app) add(inv *invocation) error {
+	mod, err := modification(inv, 0, true)
+
+	if err != nil {
+		return fmt.Errorf("todo not added: %w", err)
+	}
+
+	id, err := a.todos.AddTodoWith(mod)
+
+	if err != nil {
+		return fmt.Errorf("todo not added: %w", err)
+	}
+
+	fmt.Printf("Todo added with ID: %d\n", id)
+
+	return nil
+}
+
+// list renders the current list, or every list with --all, in the format
+// chosen by --format or --template. The arguments filter the todos.
+func (a *app) list(inv *invocation) error {
+	opts := render.Options{Format: render.FormatText, Template: inv.flag("template"), Display: a.display}
+	order := inv.flag("sort")
+
+	if inv.has("format") {
+		opts.Format = inv.flag("format")
+	}
+
+	if !render.IsFormat(opts.Format) {
+		return fmt.Errorf("unknown format %q (want one of: %s)", opts.Format, strings.Join(render.Formats, ", "))
+	}
+
+	if order == "" {
+		order = a.display.SortOrder
+	} else if !todo.IsSortOrder(order) {
+		return fmt.Errorf("unknown sort order %q (want one of: %s)", order, strings.Join(todo.SortOrders, ", "))
+	}
+
+	filter, err := todo.ParseFilter(inv.rest(0), time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	filter.IncludeHidden = inv.has("all")
+
+	if !inv.has("all") {
+		groups := []render.Group{{List: a.store.Current(), Todos: a.todos.ListTodos(order, filter)}}
+
+		if err := render.Render(os.Stdout, groups, opts); err != nil {
+			return err
+		}
+
+		// Say so when todos were left out, unless the output is for a program
+		if opts.Format == render.FormatText && opts.Template == "" {
+			filter.IncludeHidden = true
+
+			if hidden := len(a.todos.ListTodos(order, filter)) - len(groups[0].Todos); hidden > 0 {
+				fmt.Printf("(%d snoozed or waiting todos not shown; list --all shows them)\n", hidden)
+			}
+		}
+
+		return nil
+	}
+
+	names, err := a.store.Names()
+
+	if err != nil {
+		return err
+	}
+
+	var groups []render.Group
+
+	for _, name := range names {
+		tm, err := a.store.List(name)
+
+		if err != nil {
+			fmt.Printf("Skipping list %s: %v\n", name, err)
+			continue
+		}
+
+		groups = append(groups, render.Group{List: name, Todos: tm.ListTodos(order, filter)})
+	}
+
+	opts.Grouped = true
+
+	return render.Render(os.Stdout, groups, opts)
+}
+
+func (a *app) next(*invocation) error {
+	t, ok := a.todos.Next(time.Now())
+
+	if !ok {
+		fmt.Println("Nothing to do: no pending todos that aren't blocked")
+		return nil
+	}
+
+	fmt.Printf("%s\nUrgency: %.2f\n", t.Format(a.display), a.todos.Urgency(t, time.Now()).Score)
+
+	return nil
+}
+
+// explain shows how a todo's urgency score was built
+func (a *app) explain(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	t, _ := a.todos.GetTodo(id)
+	u := a.todos.Urgency(t, time.Now())
+
+	fmt.Println(t.Format(a.display))
+
+	if t.Completed {
+		fmt.Println("Completed todos have no urgency.")
+		return nil
+	}
+
+	fmt.Printf("\n%-10s %-32s %7s %7s %7s\n", "TERM", "DETAIL", "FACTOR", "WEIGHT", "VALUE")
+
+	for _, term := range u.Terms {
+		fmt.Printf("%-10s %-32s %7.3f %7.2f %7.3f\n", term.Name, term.Detail, term.Factor, term.Weight, term.Value())
+	}
+
+	fmt.Printf("%-10s %-32s %7s %7s %7.3f\n", "urgency", "", "", "", u.Score)
+
+	return nil
+}
+
+func (a *app) update(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	mod, err := modification(inv, 1, false)
+
+	if err != nil {
+		return err
+	}
+
+	if err := a.todos.ModifyTodo(id, mod); err != nil {
+		return err
+	}
+
+	fmt.Printf("Todo with ID %d updated successfully\n", id)
+
+	return nil
+}
+
+func (a *app) delete(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	if err := a.todos.DeleteTodo(id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Todo with ID %d deleted successfully\n", id)
+
+	return nil
+}
+
+func (a *app) complete(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	if err := a.todos.CompleteTodo(id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Todo with ID %d marked as completed\n", id)
+
+	return nil
+}
+
+func (a *app) incomplete(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	if err := a.todos.IncompleteTodo(id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Todo with ID %d marked as incomplete\n", id)
+
+	return nil
+}
+
+// untilDate reads a date written as until:<date> or just <date>
+func untilDate(s string, now time.Time) (time.Time, error) {
+	return dates.Parse(strings.TrimPrefix(s, "until:"), now)
+}
+
+func (a *app) snooze(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	until, err := untilDate(inv.arg(1), now)
+
+	if err != nil {
+		return err
+	}
+
+	if err := laterDay("snooze", until, now); err != nil {
+		return err
+	}
+
+	if err := a.todos.SnoozeTodo(id, until); err != nil {
+		return err
+	}
+
+	fmt.Printf("Todo with ID %d snoozed until %s\n", id, until.Format(dates.Layout))
+
+	return nil
+}
+
+// wait marks a todo as waiting. An until:<date> argument sets the
+// follow-up date, which defaults to a week from today; the remaining words
+// say who or what it waits for.
+func (a *app) wait(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	followUp := dates.Day(now).AddDate(0, 0, todo.DefaultFollowUpDays)
+	var waitingFor []string
+
+	for _, arg := range inv.args[1:] {
+		if !arg.quoted && strings.HasPrefix(arg.text, "until:") {
+			followUp, err = untilDate(arg.text, now)
+
+			if err != nil {
+				return err
+			}
+
+			if err := laterDay("wait", followUp, now); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		waitingFor = append(waitingFor, arg.text)
+	}
+
+	if err := a.todos.WaitTodo(id, strings.Join(waitingFor, " "), &followUp); err != nil {
+		return err
+	}
+
+	fmt.Printf("Todo with ID %d is waiting; it shows up again on %s\n", id, followUp.Format(dates.Layout))
+
+	return nil
+}
+
+// laterDay rejects an until: date that isn't after now, for the command
+// verb
+func laterDay(verb string, until, now time.Time) error {
+	if !until.After(now) {
+		return fmt.Errorf("can only %s until a later day, not %s", verb, until.Format(dates.Layout))
+	}
+
+	return nil
+}
+
+func (a *app) resume(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	if err := a.todos.ResumeTodo(id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Todo with ID %d is pending again\n", id)
+
+	return nil
+}
+
+// cal draws the month grid, or the agenda of a week with --week
+func (a *app) cal(inv *invocation) error {
+	now := time.Now()
+	todos := a.todos.GetAllTodos()
+
+	if inv.has("week") {
+		day := dates.Day(now)
+
+		if inv.arg(0) != "" {
+			parsed, err := dates.Parse(inv.arg(0), now)
+
+			if err != nil {
+				return err
+			}
+
+			day = parsed
+		}
+
+		return render.Agenda(os.Stdout, dates.StartOfWeek(day), todos, now, a.display)
+	}
+
+	first, err := dates.ParseMonth(inv.arg(0), now)
+
+	if err != nil {
+		return err
+	}
+
+	return render.Month(os.Stdout, first, todos, now, a.display)
+}
+
+func (a *app) focus(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	durations := []time.Duration{defaultFocus, defaultBreak}
+
+	for i, arg := range inv.rest(1) {
+		d, err := parseMinutes(arg)
+
+		if err != nil {
+			return err
+		}
+
+		durations[i] = d
+	}
+
+	focus(a.scanner, a.todos, id, durations[0], durations[1])
+
+	return nil
+}
+
+func (a *app) stats(*invocation) error {
+	printStats(a.todos)
+	return nil
+}
+
+func (a *app) annotate(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	if err := a.todos.AnnotateTodo(id, strings.Join(inv.rest(1), " ")); err != nil {
+		return err
+	}
+
+	fmt.Printf("Todo with ID %d annotated\n", id)
+
+	return nil
+}
+
+func (a *app) note(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	t, _ := a.todos.GetTodo(id)
+	note, err := editNote(id, t.Note)
+
+	if err != nil {
+		return fmt.Errorf("note not saved: %w", err)
+	}
+
+	if err := a.todos.SetNote(id, note); err != nil {
+		return fmt.Errorf("note not saved: %w", err)
+	}
+
+	fmt.Printf("Note for todo with ID %d saved\n", id)
+
+	return nil
+}
+
+func (a *app) show(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	t, _ := a.todos.GetTodo(id)
+	fmt.Println(t.Details(a.display))
+
+	return nil
+}
+
+// search prints the todos matching the query, best first, with the matched
+// text highlighted
+func (a *app) search(inv *invocation) error {
+	query := strings.Join(inv.rest(0), " ")
+	results, err := a.todos.Search(query, todo.SearchOptions{
+		CaseSensitive:    inv.has("case-sensitive"),
+		Regex:            inv.has("regex"),
+		IncludeCompleted: inv.has("include-completed"),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Printf("No todos matching %q\n", query)
+		return nil
+	}
+
+	for _, result := range results {
+		t := result.Todo
+		task := t.Task
+		var details []string
+
+		for _, match := range result.Matches {
+			switch match.Field {
+			case todo.FieldTask:
+				task = a.display.Highlight(match.Text, match.Spans)
+			case todo.FieldTag:
+				details = append(details, "+"+a.display.Highlight(match.Text, match.Spans))
+			default:
+				details = append(details, match.Field+": "+a.display.Highlight(snippet(match.Text, match.Spans)))
+			}
+		}
+
+		status := a.display.Colorize(todo.ColorYellow, "[x]")
+
+		if t.Completed {
+			status = a.display.Colorize(todo.ColorGreen, "[✓]")
+		}
+
+		fmt.Printf("%d. %s %s %s\n", t.ID, status, task, a.display.Colorize(todo.ColorDim, fmt.Sprintf("(%.2f)", result.Score)))
+
+		for _, detail := range details {
+			fmt.Printf("     %s\n", detail)
+		}
+	}
+
+	return nil
+}
+
+// snippet cuts the line around the first span out of a long text, moving
+// the spans along with it
+func snippet(text string, spans []todo.Span) (string, []todo.Span) {
+	const context = 30
+
+	first := spans[0]
+	start := strings.LastIndexByte(text[:first.Start], '\n') + 1
+	end := len(text)
+
+	if i := strings.IndexByte(text[first.End:], '\n'); i >= 0 {
+		end = first.End + i
+	}
+
+	prefix, suffix := "", ""
+
+	if first.Start-start > context {
+		start = first.Start - context
+		prefix = "…"
+
+		// Start at a word if there is one to start at
+		if i := strings.IndexByte(text[start:first.Start], ' '); i >= 0 {
+			start += i + 1
+		}
+	}
+
+	if end-first.End > context {
+		end = first.End + context
+		suffix = "…"
+	}
+
+	// Don't cut a letter in half
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	var kept []todo.Span
+
+	for _, span := range spans {
+		if span.Start >= start && span.End <= end {
+			kept = append(kept, todo.Span{Start: span.Start - start + len(prefix), End: span.End - start + len(prefix)})
+		}
+	}
+
+	return prefix + text[start:end] + suffix, kept
+}
+
+func (a *app) use(inv *invocation) error {
+	tm, err := a.store.Use(inv.arg(0))
+
+	if err != nil {
+		return fmt.Errorf("cannot switch list: %w", err)
+	}
+
+	a.todos = tm
+	fmt.Printf("Now using list: %s\n", inv.arg(0))
+
+	return nil
+}
+
+func (a *app) lists(*invocation) error {
+	names, err := a.store.Names()
+
+	if err != nil {
+		return fmt.Errorf("cannot read lists: %w", err)
+	}
+
+	fmt.Println("\n=== Your Lists ===")
+
+	for _, name := range names {
+		marker := " "
+
+		if name == a.store.Current() {
+			marker = "*"
+		}
+
+		suffix := ""
+
+		if a.store.Encrypted(name) {
+			suffix = " (encrypted)"
+		}
+
+		fmt.Printf("%s %s%s\n", marker, name, suffix)
+	}
+
+	return nil
+}
+
+func (a *app) move(inv *invocation) error {
+	id, err := a.todos.Resolve(inv.arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	newID, err := a.store.Move(id, inv.arg(1))
+
+	if err != nil {
+		return fmt.Errorf("cannot move todo: %w", err)
+	}
+
+	fmt.Printf("Todo with ID %d moved to list %s as ID %d\n", id, inv.arg(1), newID)
+
+	return nil
+}
+
+func (a *app) encrypt(*invocation) error {
+	passphrase, err := promptNewPassphrase(a.scanner)
+
+	if err != nil {
+		return fmt.Errorf("list not encrypted: %w", err)
+	}
+
+	if err := a.store.Encrypt(a.store.Current(), passphrase); err != nil {
+		return fmt.Errorf("list not encrypted: %w", err)
+	}
+
+	fmt.Printf("List %s is now stored encrypted\n", a.store.Current())
+
+	return nil
+}
+
+func (a *app) rekey(*invocation) error {
+	current, err := promptPassphrase(a.scanner, "Current passphrase: ")
+
+	if err != nil {
+		return fmt.Errorf("passphrase not changed: %w", err)
+	}
+
+	passphrase, err := promptNewPassphrase(a.scanner)
+
+	if err != nil {
+		return fmt.Errorf("passphrase not changed: %w", err)
+	}
+
+	if err := a.store.Rekey(a.store.Current(), current, passphrase); err != nil {
+		return fmt.Errorf("passphrase not changed: %w", err)
+	}
+
+	fmt.Printf("Passphrase of list %s changed\n", a.store.Current())
+
+	return nil
+}
+
+func (a *app) decrypt(*invocation) error {
+	current, err := promptPassphrase(a.scanner, "Current passphrase: ")
+
+	if err != nil {
+		return fmt.Errorf("list not decrypted: %w", err)
+	}
+
+	if err := a.store.Decrypt(a.store.Current(), current); err != nil {
+		return fmt.Errorf("list not decrypted: %w", err)
+	}
+
+	fmt.Printf("List %s is now stored as plain JSON\n", a.store.Current())
+
+	return nil
+}
+
+func (a *app) help(inv *invocation) error {
+	if inv.arg(0) == "" {
+		printHelp(a.commands, a.cfg.Aliases)
+		return nil
+	}
+
+	c, ok := findCommand(a.commands, strings.ToLower(inv.arg(0)))
+
+	if !ok {
+		return fmt.Errorf("unknown command %q", inv.arg(0))
+	}
+
+	printCommandHelp(c)
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+)
+
+// editNote opens the user's editor on a temporary Markdown file holding the
+// current note and returns the edited text. The note is in plaintext while
+// it is edited, even on an encrypted list, so the file is kept in a
+// directory only the user can read and removed afterwards.
+func editNote(id int, note string) (string, error) {
+	editor := os.Getenv("VISUAL")
+
+	if strings.TrimSpace(editor) == "" {
+		editor = os.Getenv("EDITOR")
+	}
+
+	// $EDITOR may carry arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+
+	if len(args) == 0 {
+		editor, args = "vi", []string{"vi"}
+	}
+
+	dir, err := os.MkdirTemp("", "todo-note-*")
+
+	if err != nil {
+		return "", err
+	}
+
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, fmt.Sprintf("todo-%d.md", id))
+
+	if err := os.WriteFile(name, []byte(note), 0o600); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command(args[0], append(args[1:], name)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Ctrl-C in the editor reaches this process too; outlive it so the
+	// file is still removed
+	signal.Ignore(os.Interrupt)
+	err = cmd.Run()
+	signal.Reset(os.Interrupt)
+
+	if err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(name)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), " \t\r\n"), nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neel07sanghvi/todo-cli/internal/todo"
+)
+
+const (
+	defaultFocus         = 25 * time.Minute
+	defaultBreak         = 5 * time.Minute
+	longBreak            = 15 * time.Minute
+	sessionsPerLongBreak = 4
+)
+
+// parseMinutes reads a duration such as 25m or 1h, where a bare number means minutes
+func parseMinutes(s string) (time.Duration, error) {
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return time.Duration(n) * time.Minute, nil
+	}
+
+	d, err := time.ParseDuration(s)
+
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q (try 25m, 50 or 1h)", s)
+	}
+
+	return d, nil
+}
+
+// focus runs pomodoros on a todo: a focus countdown, then a break, until
+// the user stops or completes the todo. Every finished focus period is
+// recorded on the todo; one cut short with Ctrl-C is not.
+func focus(scanner *bufio.Scanner, tm *todo.TodoManager, id int, focusFor, breakFor time.Duration) {
+	for round := 1; ; round++ {
+		t, exists := tm.GetTodo(id)
+
+		if !exists {
+			return
+		}
+
+		start := time.Now()
+
+		if !countdown(fmt.Sprintf("Focus #%d on: %s", round, t.Task), focusFor) {
+			fmt.Println("\nFocus session stopped; it was not recorded.")
+			return
+		}
+
+		if err := tm.RecordSession(id, start, time.Now()); err != nil {
+			fmt.Printf("\nSession not recorded: %v\n", err)
+		} else if t, exists := tm.GetTodo(id); exists {
+			fmt.Printf("\n\aFocus session done! %d pomodoro(s) on this todo so far.\n", len(t.Sessions))
+		}
+
+		if confirm(scanner, "Mark the todo as completed? [y/N] ", false) {
+			if err := tm.CompleteTodo(id); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			} else {
+				fmt.Printf("Todo with ID %d marked as completed\n", id)
+			}
+
+			return
+		}
+
+		rest := breakFor
+
+		if round%sessionsPerLongBreak == 0 {
+			rest = max(rest, longBreak)
+		}
+
+		if !confirm(scanner, fmt.Sprintf("Take a %s break? [Y/n] ", rest), true) {
+			return
+		}
+
+		if !countdown("Break", rest) {
+			fmt.Println("\nBreak stopped.")
+		} else {
+			fmt.Println("\n\aBreak over.")
+		}
+
+		if !confirm(scanner, "Start another focus session? [y/N] ", false) {
+			return
+		}
+	}
+}
+
+// countdown shows the time left on one line until d has passed. It returns
+// false if the user interrupted it with Ctrl-C.
+func countdown(label string, d time.Duration) bool {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	end := time.Now().Add(d)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	for {
+		left := time.Until(end).Round(time.Second)
+		fmt.Printf("\r%s  %02d:%02d left (Ctrl-C to stop) ", label, int(left.Minutes()), int(left.Seconds())%60)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			fmt.Printf("\r%s  00:00 left%s", label, strings.Repeat(" ", 20))
+			return true
+		case <-ticker.C:
+		}
+	}
+}
+
+// confirm asks a yes/no question, returning def when the answer is empty
+func confirm(scanner *bufio.Scanner, question string, def bool) bool {
+	fmt.Print(question)
+
+	if !scanner.Scan() {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+	case "":
+		return def
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// printStats reports the pomodoros per todo and per day
+func printStats(tm *todo.TodoManager) {
+	tasks, days := tm.FocusStats(time.Local)
+
+	if len(tasks) == 0 {
+		fmt.Println("No focus sessions yet. Start one with: focus <id>")
+		return
+	}
+
+	fmt.Println("\n=== Pomodoros per Todo ===")
+
+	for _, task := range tasks {
+		fmt.Printf("%3d  %5d min  %d. %s\n", task.Sessions, int(task.Total.Minutes()), task.Todo.ID, task.Todo.Task)
+	}
+
+	fmt.Println("\n=== Pomodoros per Day ===")
+
+	total := 0
+
+	for _, day := range days {
+		fmt.Printf("%s  %3d  %5d min  %s\n", day.Day.Format("Mon 2006-01-02"), day.Sessions, int(day.Total.Minutes()), strings.Repeat("●", day.Sessions))
+		total += day.Sessions
+	}
+
+	fmt.Printf("\nTotal: %d pomodoros on %d todos over %d days\n", total, len(tasks), len(days))
+}
@@ -1,23 +1,85 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
 
-func printHelp() {
+// printHelp lists every command from its spec, followed by the notes that
+// apply to all of them and the user's aliases
+func printHelp(commands []*command, aliases map[string]string) {
 	fmt.Println("\n=== Todo CLI Help ===")
 	fmt.Println("Available commands:")
-	fmt.Println("  add <task>       - Add a new todo item")
-	fmt.Println("  list             - List all todo items")
-	fmt.Println("  update <id> <task> - Update an existing todo item")
-	fmt.Println("  delete <id>      - Delete a todo item")
-	fmt.Println("  complete <id>    - Mark a todo item as completed")
-	fmt.Println("  incomplete <id>  - Mark a todo item as incomplete")
-	fmt.Println("  help             - Show this help message")
-	fmt.Println("  exit/quit        - Exit the application")
-	fmt.Println("\nExamples:")
-	fmt.Println("  add Buy groceries")
-	fmt.Println("  list")
-	fmt.Println("  update 1 Buy groceries and cook dinner")
-	fmt.Println("  complete 1")
-	fmt.Println("  incomplete 1")
-	fmt.Println("  delete 1")
+
+	for _, c := range commands {
+		fmt.Printf("  %s\n      %s\n", c.usage(), c.summary)
+	}
+
+	fmt.Println("\nType 'help <command>' for a command's flags and examples.")
+	fmt.Println("Flags go anywhere after the command, as --flag value or --flag=value;")
+	fmt.Println("quote words ('like this' or \"like this\") to keep spaces, or to use")
+	fmt.Println("them literally instead of as attributes or flags. Everything after a")
+	fmt.Println("bare -- is taken literally too.")
+	fmt.Println("\nAttributes: due:<date> pri:<H|M|L> +tag -tag depends:<id>[,<id>...]")
+	fmt.Println("An empty value (due:, pri:, depends:) clears the attribute.")
+	fmt.Println("Dates: today, tomorrow, monday..sunday, eow, eom, +3d, +2w, -1d, 2024-03-15")
+	fmt.Println("\nWherever <id> is expected you can also give an unambiguous prefix of the")
+	fmt.Println("todo's UUID (shown by 'show'), e.g. 'complete 3f2a'.")
+	fmt.Println("\nPut a .todo-list file containing a list name in a project directory")
+	fmt.Println("to use that list whenever the CLI is started from there.")
+
+	if len(aliases) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(aliases))
+
+	for name := range aliases {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	fmt.Println("\nAliases (from your config file):")
+
+	for _, name := range names {
+		fmt.Printf("  %-16s = %s\n", name, aliases[name])
+	}
+}
+
+// printCommandHelp shows the usage, flags and examples of one command
+func printCommandHelp(c *command) {
+	fmt.Printf("\nUsage: %s\n  %s\n", c.usage(), c.summary)
+
+	if len(c.aliases) > 0 {
+		fmt.Printf("\nAlso: %s\n", strings.Join(c.aliases, ", "))
+	}
+
+	if len(c.flags) > 0 {
+		fmt.Println("\nFlags:")
+
+		for _, f := range c.flags {
+			fmt.Printf("  %-32s %s\n", strings.TrimSpace("--"+f.name+" "+f.value), f.usage)
+		}
+	}
+
+	if len(c.examples) > 0 {
+		fmt.Println("\nExamples:")
+
+		for _, example := range c.examples {
+			fmt.Printf("  %s\n", example)
+		}
+	}
+}
+
+// commandNames lists the commands for the welcome banner
+func commandNames(commands []*command) string {
+	names := make([]string, 0, len(commands))
+
+	for _, c := range commands {
+		names = append(names, strings.Join(append([]string{c.name}, c.aliases...), "/"))
+	}
+
+	return strings.Join(names, ", ")
 }
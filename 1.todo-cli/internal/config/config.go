@@ -0,0 +1,329 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neel07sanghvi/todo-cli/internal/todo"
+)
+
+// Color modes accepted by the color setting
+const (
+	ColorAuto = "auto"
+	ColorOn   = "on"
+	ColorOff  = "off"
+)
+
+// Config holds the user's settings for the todo CLI
+type Config struct {
+	DataDir     string        // where the todo lists are stored; empty means the default
+	DateFormat  string        // Go time layout used when printing dates
+	SortOrder   string        // default order of the list command
+	Color       string        // auto, on or off
+	HooksDir    string        // where lifecycle hooks live; empty means the default
+	HookTimeout time.Duration // how long a hook may run
+	Urgency     todo.UrgencyWeights
+	Aliases     map[string]string // command name -> replacement text
+}
+
+// ParseError reports a problem on a specific line of the config file
+type ParseError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Default returns the settings used when there is no config file
+func Default() *Config {
+	return &Config{
+		DateFormat:  "2006-01-02 15:04",
+		SortOrder:   todo.SortByID,
+		Color:       ColorAuto,
+		HookTimeout: 5 * time.Second,
+		Urgency:     todo.DefaultUrgencyWeights(),
+		Aliases:     make(map[string]string),
+	}
+}
+
+// Path returns the location of the config file. TODO_CONFIG overrides the
+// default of $XDG_CONFIG_HOME/todo-cli/config.
+func Path() (string, error) {
+	if path := os.Getenv("TODO_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "todo-cli", "config"), nil
+}
+
+// Load reads the config file at path. A missing file is not an error; the
+// defaults are returned instead.
+func Load(path string) (*Config, error) {
+	file, err := os.Open(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return Default(), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return Parse(file, path)
+}
+
+// Parse reads settings in the form
+//
+//	# comment
+//	date_format = 02 Jan 2006
+//	sort = created
+//
+//	[aliases]
+//	d = delete
+//	today = list due:today
+//
+//	[urgency]
+//	priority.h = 8
+//
+// name is only used in error messages.
+func Parse(r io.Reader, name string) (*Config, error) {
+	cfg := Default()
+	scanner := bufio.NewScanner(r)
+	section := ""
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		fail := func(format string, args ...any) error {
+			return &ParseError{File: name, Line: lineNo, Msg: fmt.Sprintf(format, args...)}
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fail("unterminated section header %q", line)
+			}
+
+			section = strings.TrimSpace(line[1 : len(line)-1])
+
+			if section != "aliases" && section != "urgency" {
+				return nil, fail("unknown section [%s]", section)
+			}
+
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+
+		if !ok {
+			return nil, fail("expected key = value, got %q", line)
+		}
+
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if key == "" {
+			return nil, fail("missing key before '='")
+		}
+
+		if section == "aliases" {
+			if strings.ContainsAny(key, " \t") {
+				return nil, fail("alias name %q must be a single word", key)
+			}
+
+			if value == "" {
+				return nil, fail("alias %q has no command", key)
+			}
+
+			cfg.Aliases[strings.ToLower(key)] = value
+			continue
+		}
+
+		if section == "urgency" {
+			if err := cfg.setWeight(key, value); err != nil {
+				return nil, fail("%v", err)
+			}
+
+			continue
+		}
+
+		if err := cfg.set(key, value); err != nil {
+			return nil, fail("%v", err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// set applies a single top-level setting
+func (c *Config) set(key, value string) error {
+	switch key {
+	case "data_dir":
+		if value == "" {
+			return fmt.Errorf("data_dir must not be empty")
+		}
+
+		c.DataDir = expandHome(value)
+
+	case "date_format":
+		// A layout without any fields formats every time as itself
+		sample := time.Date(2001, time.March, 4, 5, 6, 7, 0, time.UTC)
+
+		if value == "" || sample.Format(value) == value {
+			return fmt.Errorf("date_format %q contains no date or time fields (use a Go layout such as 2006-01-02 15:04)", value)
+		}
+
+		c.DateFormat = value
+
+	case "sort":
+		if !todo.IsSortOrder(value) {
+			return fmt.Errorf("unknown sort order %q (want one of: %s)", value, strings.Join(todo.SortOrders, ", "))
+		}
+
+		c.SortOrder = value
+
+	case "color":
+		switch strings.ToLower(value) {
+		case ColorAuto:
+			c.Color = ColorAuto
+		case ColorOn, "true", "yes":
+			c.Color = ColorOn
+		case ColorOff, "false", "no":
+			c.Color = ColorOff
+		default:
+			return fmt.Errorf("color must be auto, on or off, got %q", value)
+		}
+
+	case "hooks_dir":
+		if value == "" {
+			return fmt.Errorf("hooks_dir must not be empty")
+		}
+
+		c.HooksDir = expandHome(value)
+
+	case "hook_timeout":
+		timeout, err := time.ParseDuration(value)
+
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("hook_timeout must be a positive duration such as 5s, got %q", value)
+		}
+
+		c.HookTimeout = timeout
+
+	default:
+		return fmt.Errorf("unknown setting %q", key)
+	}
+
+	return nil
+}
+
+// setWeight applies a setting from the [urgency] section
+func (c *Config) setWeight(key, value string) error {
+	weights := map[string]*float64{
+		"due":        &c.Urgency.Due,
+		"priority.h": &c.Urgency.PriorityH,
+		"priority.m": &c.Urgency.PriorityM,
+		"priority.l": &c.Urgency.PriorityL,
+		"age":        &c.Urgency.Age,
+		"tags":       &c.Urgency.Tags,
+		"blocked":    &c.Urgency.Blocked,
+	}
+
+	weight, ok := weights[strings.ToLower(key)]
+
+	if !ok {
+		return fmt.Errorf("unknown urgency weight %q (want due, priority.h, priority.m, priority.l, age, tags or blocked)", key)
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		return fmt.Errorf("urgency weight %s must be a number, got %q", key, value)
+	}
+
+	*weight = parsed
+
+	return nil
+}
+
+// Display returns the display preferences, resolving the color mode
+func (c *Config) Display() todo.Display {
+	return todo.Display{DateFormat: c.DateFormat, Color: c.UseColor(), SortOrder: c.SortOrder}
+}
+
+// UseColor resolves the color mode, enabling color in auto mode only when
+// stdout is a terminal and NO_COLOR is unset
+func (c *Config) UseColor() bool {
+	switch c.Color {
+	case ColorOn:
+		return true
+	case ColorOff:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	info, err := os.Stdout.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ExpandAlias replaces the first word of input with its alias, if one is
+// defined. Aliases are expanded once, so `list = list --all` is allowed.
+func (c *Config) ExpandAlias(input string) string {
+	name, rest, _ := strings.Cut(input, " ")
+	replacement, ok := c.Aliases[strings.ToLower(name)]
+
+	if !ok {
+		return input
+	}
+
+	if rest == "" {
+		return replacement
+	}
+
+	return replacement + " " + rest
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
@@ -0,0 +1,149 @@
+package dates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout is how dates without a time of day (due dates, snooze dates) are written
+const Layout = "2006-01-02"
+
+// Day returns midnight at the start of t's day in t's location
+func Day(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Parse turns a date typed by the user into midnight of that day, in the
+// location of now. It understands:
+//
+//	today, tomorrow, yesterday (or tod, tom)
+//	monday ... sunday (or mon ... sun) - the next such day after today
+//	eow, eom - the last day of this week (Sunday) or month
+//	+3d, +2w, +1m, -1d - an offset from today in days, weeks or months
+//	2024-03-15 - a calendar date
+func Parse(s string, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	today := Day(now)
+
+	switch s {
+	case "today", "tod", "now":
+		return today, nil
+	case "tomorrow", "tom":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "eow":
+		return today.AddDate(0, 0, (7-int(today.Weekday()))%7), nil
+	case "eom":
+		return today.AddDate(0, 1, -today.Day()), nil
+	}
+
+	if weekday, ok := parseWeekday(s); ok {
+		days := (int(weekday) - int(today.Weekday()) + 7) % 7
+
+		if days == 0 {
+			days = 7
+		}
+
+		return today.AddDate(0, 0, days), nil
+	}
+
+	if len(s) > 2 && (s[0] == '+' || s[0] == '-') {
+		n, err := strconv.Atoi(s[1 : len(s)-1])
+
+		if err == nil {
+			if s[0] == '-' {
+				n = -n
+			}
+
+			switch s[len(s)-1] {
+			case 'd':
+				return today.AddDate(0, 0, n), nil
+			case 'w':
+				return today.AddDate(0, 0, 7*n), nil
+			case 'm':
+				return today.AddDate(0, n, 0), nil
+			}
+		}
+	}
+
+	if t, err := time.ParseInLocation(Layout, s, now.Location()); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognised date %q (try today, friday, +3d or %s)", s, Layout)
+}
+
+// ParseMonth turns a month typed by the user into midnight of its first day.
+// It understands an empty string or "this" (the current month), next, prev
+// or last, month names (march, mar - in the current year), month numbers
+// (3) and 2024-03.
+func ParseMonth(s string, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	year, month, _ := now.Date()
+	first := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+
+	switch s {
+	case "", "this":
+		return first, nil
+	case "next":
+		return first.AddDate(0, 1, 0), nil
+	case "prev", "last":
+		return first.AddDate(0, -1, 0), nil
+	}
+
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+
+		if s == name || s == name[:3] {
+			return time.Date(year, m, 1, 0, 0, 0, 0, now.Location()), nil
+		}
+	}
+
+	if n, err := strconv.Atoi(s); err == nil && n >= 1 && n <= 12 {
+		return time.Date(year, time.Month(n), 1, 0, 0, 0, 0, now.Location()), nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01", s, now.Location()); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognised month %q (try next, march, 3 or 2024-03)", s)
+}
+
+// StartOfWeek returns midnight of the Monday of t's week
+func StartOfWeek(t time.Time) time.Time {
+	day := Day(t)
+	offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+
+	return day.AddDate(0, 0, -offset)
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+
+		if s == name || s == name[:3] {
+			return day, true
+		}
+	}
+
+	return 0, false
+}
+
+// DaysBetween returns the number of calendar days from a to b, ignoring the
+// time of day and any daylight saving shifts in between
+func DaysBetween(a, b time.Time) int {
+	a, b = Day(a), Day(b)
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+
+	// Compare the dates as if they were in UTC, where every day is 24h long
+	ua := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)
+	ub := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)
+
+	return int(ub.Sub(ua).Hours() / 24)
+}
@@ -0,0 +1,191 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neel07sanghvi/todo-cli/internal/todo"
+)
+
+// DefaultTimeout is how long a single hook may run before it is killed
+const DefaultTimeout = 5 * time.Second
+
+// Input is the JSON document written to a hook's stdin
+type Input struct {
+	Event string     `json:"event"`
+	List  string     `json:"list"`
+	Old   *todo.Todo `json:"old"` // null for add
+	New   *todo.Todo `json:"new"` // null for delete
+}
+
+// Error reports a hook that rejected a change or failed to run
+type Error struct {
+	Hook string
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("hook %s %s", e.Hook, e.Msg)
+}
+
+// Runner runs the executable hooks found in a directory. A hook for an
+// event is any executable named on-<event> or starting with on-<event>.
+// or on-<event>- (e.g. on-add.py, on-complete-notify); several hooks for
+// the same event run in name order, each seeing the previous one's output.
+type Runner struct {
+	dir     string
+	timeout time.Duration
+}
+
+// DefaultDir returns $XDG_CONFIG_HOME/todo-cli/hooks
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "todo-cli", "hooks"), nil
+}
+
+// New creates a Runner for the hooks in dir
+func New(dir string, timeout time.Duration) *Runner {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Runner{dir: dir, timeout: timeout}
+}
+
+// ForList returns a todo.HookFunc that runs the hooks for changes in the named list
+func (r *Runner) ForList(list string) todo.HookFunc {
+	return func(event string, old, updated *todo.Todo) (*todo.Todo, error) {
+		return r.Run(event, list, old, updated)
+	}
+}
+
+// Run passes a change through every hook for event. A hook rejects the
+// change by exiting non-zero; its stderr (or stdout) becomes the reason.
+// A hook may print a todo as JSON to replace the new version of the todo.
+func (r *Runner) Run(event, list string, old, updated *todo.Todo) (*todo.Todo, error) {
+	paths, err := r.find(event)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		result, err := r.runOne(path, Input{Event: event, List: list, Old: old, New: updated})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if result != nil && updated != nil {
+			updated = result
+		}
+	}
+
+	return updated, nil
+}
+
+// find lists the executables in the hooks directory that handle event
+func (r *Runner) find(event string) ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read hooks directory: %w", err)
+	}
+
+	prefix := "on-" + event
+	var paths []string
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		if name != prefix && !strings.HasPrefix(name, prefix+".") && !strings.HasPrefix(name, prefix+"-") {
+			continue
+		}
+
+		info, err := entry.Info()
+
+		if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+			continue
+		}
+
+		paths = append(paths, filepath.Join(r.dir, name))
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+func (r *Runner) runOne(path string, input Input) (*todo.Todo, error) {
+	name := filepath.Base(path)
+
+	payload, err := json.Marshal(input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, path, input.Event)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait forever on grandchildren that keep the output pipes open
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, &Error{Hook: name, Msg: fmt.Sprintf("timed out after %s", r.timeout)}
+	}
+
+	if err != nil {
+		reason := strings.TrimSpace(stderr.String())
+
+		if reason == "" {
+			reason = strings.TrimSpace(stdout.String())
+		}
+
+		if reason == "" {
+			reason = err.Error()
+		}
+
+		return nil, &Error{Hook: name, Msg: "rejected the change: " + reason}
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+
+	if len(output) == 0 {
+		return nil, nil
+	}
+
+	var result todo.Todo
+
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, &Error{Hook: name, Msg: fmt.Sprintf("printed invalid JSON: %v", err)}
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,198 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neel07sanghvi/todo-cli/internal/dates"
+	"github.com/neel07sanghvi/todo-cli/internal/todo"
+)
+
+// Day markers used in the month grid, in order of precedence
+const (
+	markOverdue   = '!'
+	markDue       = '*'
+	markCompleted = '+'
+)
+
+// dayTodos collects the todos due and completed on each day, keyed by the
+// day as written in dates.Layout in the local time zone. Times make poor
+// keys: the same day compares unequal in another location or with a
+// monotonic clock reading.
+type dayTodos struct {
+	due       map[string][]*todo.Todo
+	completed map[string][]*todo.Todo
+}
+
+func collectDays(todos []*todo.Todo, loc *time.Location) dayTodos {
+	days := dayTodos{
+		due:       make(map[string][]*todo.Todo),
+		completed: make(map[string][]*todo.Todo),
+	}
+
+	for _, t := range todos {
+		if t.Due != nil && !t.Completed {
+			day := dayKey(t.Due.In(loc))
+			days.due[day] = append(days.due[day], t)
+		}
+
+		if t.Completed && t.CompletedAt != nil {
+			day := dayKey(t.CompletedAt.In(loc))
+			days.completed[day] = append(days.completed[day], t)
+		}
+	}
+
+	return days
+}
+
+// dayKey returns the key of t's day in dayTodos. Keys sort in date order.
+func dayKey(t time.Time) string {
+	return t.Format(dates.Layout)
+}
+
+// Month draws a calendar grid for the month containing first, marking days
+// with pending (*), overdue (!) and completed (+) todos and bracketing today
+func Month(w io.Writer, first time.Time, todos []*todo.Todo, now time.Time, d todo.Display) error {
+	loc := now.Location()
+	first = time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, loc)
+	today := dates.Day(now)
+	days := collectDays(todos, loc)
+
+	title := first.Format("January 2006")
+	const gridWidth = 7 * 5
+
+	fmt.Fprintf(w, "\n%*s\n", (gridWidth+len(title))/2, title)
+	fmt.Fprintln(w, " Mo   Tu   We   Th   Fr   Sa   Su")
+
+	var line strings.Builder
+
+	// Pad up to the first day's weekday, weeks starting on Monday
+	line.WriteString(strings.Repeat("     ", (int(first.Weekday())+6)%7))
+
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		line.WriteString(dayCell(day, today, days, d))
+
+		if day.Weekday() == time.Sunday {
+			fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+			line.Reset()
+		}
+	}
+
+	if line.Len() > 0 {
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+
+	fmt.Fprintf(w, "\n[dd ] today  %c due  %c overdue  %c completed\n", markDue, markOverdue, markCompleted)
+
+	// List what's due this month under the grid
+	heading := false
+
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		for _, t := range days.due[dayKey(day)] {
+			if !heading {
+				fmt.Fprintln(w, "\nDue this month:")
+				heading = true
+			}
+
+			fmt.Fprintf(w, "  %s  %d. %s\n", day.Format("Mon 02"), t.ID, t.Task)
+		}
+	}
+
+	return nil
+}
+
+// dayCell renders one day of the grid as five characters: the day number
+// followed by its marker, in brackets for today
+func dayCell(day, today time.Time, days dayTodos, d todo.Display) string {
+	left, right := " ", " "
+
+	if day.Equal(today) {
+		left, right = "[", "]"
+	}
+
+	mark, color := " ", ""
+
+	switch {
+	case len(days.due[dayKey(day)]) > 0 && day.Before(today):
+		mark, color = string(markOverdue), todo.ColorRed
+	case len(days.due[dayKey(day)]) > 0:
+		mark, color = string(markDue), todo.ColorYellow
+	case len(days.completed[dayKey(day)]) > 0:
+		mark, color = string(markCompleted), todo.ColorGreen
+	}
+
+	number := fmt.Sprintf("%2d", day.Day())
+
+	if day.Equal(today) {
+		number = d.Colorize(todo.ColorInvert, number)
+	} else if color != "" {
+		number = d.Colorize(color, number)
+	}
+
+	return left + number + mark + right
+}
+
+// Agenda lists the todos due and completed on each day of the week that
+// starts on monday, preceded by any todos that are already overdue
+func Agenda(w io.Writer, monday time.Time, todos []*todo.Todo, now time.Time, d todo.Display) error {
+	loc := now.Location()
+	today := dates.Day(now)
+	days := collectDays(todos, loc)
+
+	var overdue []*todo.Todo
+
+	for day, dueTodos := range days.due {
+		if day < dayKey(today) && day < dayKey(monday) {
+			overdue = append(overdue, dueTodos...)
+		}
+	}
+
+	sort.Slice(overdue, func(i, j int) bool { return overdue[i].Due.Before(*overdue[j].Due) })
+
+	sunday := monday.AddDate(0, 0, 6)
+	fmt.Fprintf(w, "\n=== Week of %s - %s ===\n", monday.Format("Mon 02 Jan"), sunday.Format("Mon 02 Jan 2006"))
+
+	if len(overdue) > 0 {
+		fmt.Fprintln(w, d.Colorize(todo.ColorRed, "\nOverdue"))
+
+		for _, t := range overdue {
+			fmt.Fprintf(w, "  due %s  %d. %s\n", t.Due.In(loc).Format(dates.Layout), t.ID, t.Task)
+		}
+	}
+
+	for day := monday; !day.After(sunday); day = day.AddDate(0, 0, 1) {
+		heading := day.Format("Mon 02 Jan")
+
+		if day.Equal(today) {
+			heading = d.Colorize(todo.ColorBold, heading+" (today)")
+		}
+
+		fmt.Fprintf(w, "\n%s\n", heading)
+
+		due, completed := days.due[dayKey(day)], days.completed[dayKey(day)]
+
+		if len(due) == 0 && len(completed) == 0 {
+			fmt.Fprintln(w, "  -")
+			continue
+		}
+
+		for _, t := range due {
+			label := "due "
+
+			if day.Before(today) {
+				label = d.Colorize(todo.ColorRed, "late")
+			}
+
+			fmt.Fprintf(w, "  %s  %d. %s\n", label, t.ID, t.Task)
+		}
+
+		for _, t := range completed {
+			fmt.Fprintf(w, "  %s  %d. %s\n", d.Colorize(todo.ColorGreen, "done"), t.ID, t.Task)
+		}
+	}
+
+	return nil
+}
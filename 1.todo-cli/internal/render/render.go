@@ -0,0 +1,165 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/neel07sanghvi/todo-cli/internal/todo"
+)
+
+// Output formats understood by Render
+const (
+	FormatText   = "text"
+	FormatTable  = "table"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// Formats lists every valid output format
+var Formats = []string{FormatText, FormatTable, FormatJSON, FormatNDJSON}
+
+// Group is the todos of one list
+type Group struct {
+	List  string
+	Todos []*todo.Todo
+}
+
+// Options controls how todos are rendered
+type Options struct {
+	Format   string // one of Formats; ignored when Template is set
+	Template string // text/template executed once per todo
+	Grouped  bool   // show which list each todo belongs to
+	Width    int    // terminal width for the table format; 0 means detect
+	Display  todo.Display
+}
+
+// Item is what templates and the JSON formats see for each todo: the todo's
+// own fields plus the name of its list
+type Item struct {
+	List string `json:"list"`
+	*todo.Todo
+}
+
+// IsFormat reports whether format is one of Formats
+func IsFormat(format string) bool {
+	return slices.Contains(Formats, format)
+}
+
+// Render writes the todos of groups to w
+func Render(w io.Writer, groups []Group, opts Options) error {
+	if opts.Template != "" {
+		return renderTemplate(w, groups, opts.Template)
+	}
+
+	switch opts.Format {
+	case FormatText, "":
+		return renderText(w, groups, opts.Grouped, opts.Display)
+	case FormatTable:
+		width := opts.Width
+
+		if width <= 0 {
+			width = TerminalWidth()
+		}
+
+		return renderTable(w, groups, opts.Grouped, width, opts.Display)
+	case FormatJSON:
+		items := flatten(groups)
+
+		if items == nil {
+			items = []Item{}
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(items)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+
+		for _, item := range flatten(groups) {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q (want one of: %s)", opts.Format, strings.Join(Formats, ", "))
+	}
+}
+
+// flatten turns groups into the Items seen by templates and JSON output
+func flatten(groups []Group) []Item {
+	var items []Item
+
+	for _, g := range groups {
+		for _, t := range g.Todos {
+			items = append(items, Item{List: g.List, Todo: t})
+		}
+	}
+
+	return items
+}
+
+// renderText is the classic one-line-per-todo listing
+func renderText(w io.Writer, groups []Group, grouped bool, d todo.Display) error {
+	total, completed := 0, 0
+
+	if !grouped && len(groups) == 1 && len(groups[0].Todos) == 0 {
+		_, err := fmt.Fprintln(w, "No todos found. Add some todos to get started!")
+		return err
+	}
+
+	for _, g := range groups {
+		if grouped {
+			fmt.Fprintf(w, "\n=== %s ===\n", g.List)
+
+			if len(g.Todos) == 0 {
+				fmt.Fprintln(w, "(empty)")
+			}
+		} else {
+			fmt.Fprintln(w, "\n=== Your Todos ===")
+		}
+
+		for _, t := range g.Todos {
+			fmt.Fprintln(w, t.Format(d))
+
+			if t.Completed {
+				completed++
+			}
+		}
+
+		total += len(g.Todos)
+	}
+
+	_, err := fmt.Fprintf(w, "\nTotal: %d | Completed: %d | Remaining: %d\n", total, completed, total-completed)
+
+	return err
+}
+
+func renderTemplate(w io.Writer, groups []Group, text string) error {
+	// Users type \n and \t on the command line; make them mean what they say
+	text = strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(text)
+
+	tmpl, err := template.New("todo").Parse(text)
+
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+
+	for _, item := range flatten(groups) {
+		if err := tmpl.Execute(w, item); err != nil {
+			return fmt.Errorf("template: %w", err)
+		}
+
+		if !strings.HasSuffix(text, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/neel07sanghvi/todo-cli/internal/dates"
+	"github.com/neel07sanghvi/todo-cli/internal/todo"
+)
+
+const (
+	columnGap    = "  "
+	minTaskWidth = 10
+)
+
+// renderTable lays the todos out in aligned columns. Every column except
+// the task is as wide as its widest value; the task column gets whatever is
+// left of the terminal width and long tasks are cut short.
+func renderTable(w io.Writer, groups []Group, grouped bool, width int, d todo.Display) error {
+	header := []string{"ID", "STATUS", "TASK", "PRI", "DUE", "CREATED", "COMPLETED"}
+	taskColumn := 2
+
+	if grouped {
+		header = append([]string{"LIST"}, header...)
+		taskColumn++
+	}
+
+	rows := [][]string{header}
+
+	for _, g := range groups {
+		for _, t := range g.Todos {
+			row := []string{fmt.Sprint(t.ID), status(t), t.Task, t.Priority, "", t.CreatedAt.Format(d.DateFormat), ""}
+
+			if t.Due != nil {
+				row[4] = t.Due.Format(dates.Layout)
+			}
+
+			if t.CompletedAt != nil {
+				row[6] = t.CompletedAt.Format(d.DateFormat)
+			}
+
+			if grouped {
+				row = append([]string{g.List}, row...)
+			}
+
+			rows = append(rows, row)
+		}
+	}
+
+	if len(rows) == 1 {
+		_, err := fmt.Fprintln(w, "No todos found.")
+		return err
+	}
+
+	widths := make([]int, len(header))
+
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	fixed := len(columnGap) * (len(widths) - 1)
+
+	for i, columnWidth := range widths {
+		if i != taskColumn {
+			fixed += columnWidth
+		}
+	}
+
+	widths[taskColumn] = min(widths[taskColumn], max(width-fixed, minTaskWidth))
+
+	for _, row := range rows {
+		cells := make([]string, len(row))
+
+		for i, cell := range row {
+			cells[i] = pad(truncate(cell, widths[i]), widths[i])
+		}
+
+		line := strings.TrimRight(strings.Join(cells, columnGap), " ")
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func status(t *todo.Todo) string {
+	if t.Completed {
+		return "done"
+	}
+
+	return t.State(time.Now())
+}
+
+// truncate shortens s to at most width runes, marking the cut with an ellipsis
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+
+	runes := []rune(s)
+
+	return string(runes[:width-1]) + "…"
+}
+
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+}
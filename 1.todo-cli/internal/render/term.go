@@ -0,0 +1,22 @@
+package render
+
+import (
+	"os"
+	"strconv"
+)
+
+const defaultWidth = 80
+
+// TerminalWidth returns the width of the terminal: $COLUMNS if set, the
+// size reported by the terminal otherwise, or 80 when output isn't a terminal
+func TerminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+
+	if width := ttyWidth(); width > 0 {
+		return width
+	}
+
+	return defaultWidth
+}
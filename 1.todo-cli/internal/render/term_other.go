@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package render
+
+// ttyWidth can't query the terminal on this platform
+func ttyWidth() int {
+	return 0
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Encrypted list files start with this header:
+//
+//	magic (8) | PBKDF2 iterations (4, big endian) | salt (16) | nonce (12)
+//
+// followed by the AES-256-GCM sealed JSON. The whole header is passed to GCM
+// as additional data, so changing the salt or iteration count is detected
+// just like changing the ciphertext.
+const (
+	encMagic          = "TODOENC1"
+	saltSize          = 16
+	keySize           = 32
+	headerSize        = len(encMagic) + 4 + saltSize
+	defaultIterations = 600_000
+	maxIterations     = 10_000_000
+)
+
+// ErrDecrypt means an encrypted list could not be opened. GCM can't tell a
+// wrong passphrase from a modified file, so the message covers both.
+var ErrDecrypt = errors.New("wrong passphrase or the file has been tampered with")
+
+// cipherKey is a key derived from a passphrase, kept so a list can be
+// re-encrypted on every save without running PBKDF2 again
+type cipherKey struct {
+	salt       []byte
+	iterations uint32
+	key        []byte
+}
+
+func deriveKey(passphrase string, salt []byte, iterations uint32) (*cipherKey, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, int(iterations), keySize)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &cipherKey{salt: salt, iterations: iterations, key: key}, nil
+}
+
+// newKey derives a key from passphrase using a fresh random salt
+func newKey(passphrase string) (*cipherKey, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+
+	salt := make([]byte, saltSize)
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return deriveKey(passphrase, salt, defaultIterations)
+}
+
+// matches reports whether passphrase is the one k was derived from
+func (k *cipherKey) matches(passphrase string) bool {
+	other, err := deriveKey(passphrase, k.salt, k.iterations)
+
+	return err == nil && subtle.ConstantTimeCompare(k.key, other.key) == 1
+}
+
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encMagic))
+}
+
+// seal encrypts plaintext into the encrypted file format
+func seal(k *cipherKey, plaintext []byte) ([]byte, error) {
+	aead, err := newGCM(k.key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, headerSize+aead.NonceSize())
+	header = append(header, encMagic...)
+	header = binary.BigEndian.AppendUint32(header, k.iterations)
+	header = append(header, k.salt...)
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header = append(header, nonce...)
+
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// unseal decrypts data written by seal and returns the plaintext together
+// with the key, so the caller can seal the list again later
+func unseal(passphrase string, data []byte) ([]byte, *cipherKey, error) {
+	if len(data) < headerSize || !isEncrypted(data) {
+		return nil, nil, ErrDecrypt
+	}
+
+	iterations := binary.BigEndian.Uint32(data[len(encMagic):])
+
+	if iterations == 0 || iterations > maxIterations {
+		return nil, nil, ErrDecrypt
+	}
+
+	salt := bytes.Clone(data[len(encMagic)+4 : headerSize])
+	k, err := deriveKey(passphrase, salt, iterations)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aead, err := newGCM(k.key)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(data) < headerSize+aead.NonceSize() {
+		return nil, nil, ErrDecrypt
+	}
+
+	header := data[:headerSize+aead.NonceSize()]
+	nonce := header[headerSize:]
+
+	plaintext, err := aead.Open(nil, nonce, data[len(header):], header)
+
+	if err != nil {
+		return nil, nil, ErrDecrypt
+	}
+
+	return plaintext, k, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, fmt.Errorf("init cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,451 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/neel07sanghvi/todo-cli/internal/todo"
+)
+
+const (
+	// DefaultList is the list used when nothing else has been selected
+	DefaultList = "default"
+
+	// OverrideFile can be placed in a directory (e.g. a project repo) to pin
+	// the list used whenever the CLI is started from inside that directory
+	OverrideFile = ".todo-list"
+
+	currentFile = "current"
+	listsDir    = "lists"
+)
+
+var listNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// PassphraseFunc is asked for the passphrase of an encrypted list when it is loaded
+type PassphraseFunc func(list string) (string, error)
+
+// Store keeps every named todo list in its own file under a data directory
+type Store struct {
+	dir        string
+	current    string
+	override   string // path of the override file that picked the current list, if any
+	lists      map[string]*todo.TodoManager
+	keys       map[string]*cipherKey // keys of the loaded lists that are encrypted
+	passphrase PassphraseFunc
+	hooks      func(list string) todo.HookFunc
+	weights    todo.UrgencyWeights
+}
+
+// DefaultDir returns the data directory used when none is configured
+func DefaultDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "todo-cli"), nil
+	}
+
+	home, err := os.UserHomeDir()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "share", "todo-cli"), nil
+}
+
+// Open prepares the data directory and selects the current list. A
+// .todo-list file in the working directory or any of its parents takes
+// precedence over the list last chosen with Use.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, listsDir), 0o755); err != nil {
+		return nil, fmt.Errorf("create data directory: %w", err)
+	}
+
+	s := &Store{
+		dir:     dir,
+		current: DefaultList,
+		lists:   make(map[string]*todo.TodoManager),
+		keys:    make(map[string]*cipherKey),
+		weights: todo.DefaultUrgencyWeights(),
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, currentFile))
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(string(data)); name != "" {
+		if err := ValidateName(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Join(dir, currentFile), err)
+		}
+
+		s.current = name
+	}
+
+	name, path, err := findOverride()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		s.current = name
+		s.override = path
+	}
+
+	return s, nil
+}
+
+// ValidateName reports whether name can be used as a list name
+func ValidateName(name string) error {
+	if !listNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid list name %q: use letters, digits, '-' and '_' only", name)
+	}
+
+	return nil
+}
+
+// SetPassphraseFunc sets how passphrases for encrypted lists are obtained
+func (s *Store) SetPassphraseFunc(fn PassphraseFunc) {
+	s.passphrase = fn
+}
+
+// SetHooks sets the function that provides the lifecycle hook of each list
+func (s *Store) SetHooks(fn func(list string) todo.HookFunc) {
+	s.hooks = fn
+
+	for name, tm := range s.lists {
+		tm.SetHook(fn(name))
+	}
+}
+
+// SetUrgencyWeights sets the urgency weights of every list
+func (s *Store) SetUrgencyWeights(w todo.UrgencyWeights) {
+	s.weights = w
+
+	for _, tm := range s.lists {
+		tm.SetUrgencyWeights(w)
+	}
+}
+
+// Current returns the name of the list commands operate on
+func (s *Store) Current() string {
+	return s.current
+}
+
+// Override returns the path of the per-directory override file in effect, if any
+func (s *Store) Override() string {
+	return s.override
+}
+
+// List returns the named list, loading it from disk the first time it is
+// requested. Lists that don't exist yet start out empty.
+func (s *Store) List(name string) (*todo.TodoManager, error) {
+	if tm, ok := s.lists[name]; ok {
+		return tm, nil
+	}
+
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+
+	tm := todo.NewTodoManager()
+	data, err := os.ReadFile(s.listPath(name))
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// New list, nothing to load
+	case err != nil:
+		return nil, err
+	case isEncrypted(data):
+		if s.passphrase == nil {
+			return nil, fmt.Errorf("list %q is encrypted and no passphrase is available", name)
+		}
+
+		passphrase, err := s.passphrase(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		plaintext, key, err := unseal(passphrase, data)
+
+		if err != nil {
+			// The list is never cached, so Save can't overwrite the file
+			return nil, fmt.Errorf("load list %q: %w (file left unchanged)", name, err)
+		}
+
+		if err := json.Unmarshal(plaintext, tm); err != nil {
+			return nil, fmt.Errorf("load list %q: %w", name, err)
+		}
+
+		s.keys[name] = key
+	default:
+		if err := json.Unmarshal(data, tm); err != nil {
+			return nil, fmt.Errorf("load list %q: %w", name, err)
+		}
+	}
+
+	if s.hooks != nil {
+		tm.SetHook(s.hooks(name))
+	}
+
+	tm.SetUrgencyWeights(s.weights)
+	s.lists[name] = tm
+
+	return tm, nil
+}
+
+// Use switches to the named list and remembers it for the next start
+func (s *Store) Use(name string) (*todo.TodoManager, error) {
+	tm, err := s.List(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeFile(filepath.Join(s.dir, currentFile), []byte(name+"\n")); err != nil {
+		return nil, err
+	}
+
+	s.current = name
+	s.override = ""
+
+	return tm, nil
+}
+
+// Encrypted reports whether the named list is stored encrypted. Only lists
+// that have been loaded are considered.
+func (s *Store) Encrypted(name string) bool {
+	return s.keys[name] != nil
+}
+
+// Encrypt starts storing the named list encrypted with passphrase
+func (s *Store) Encrypt(name, passphrase string) error {
+	if _, err := s.List(name); err != nil {
+		return err
+	}
+
+	if s.Encrypted(name) {
+		return fmt.Errorf("list %q is already encrypted; use rekey to change its passphrase", name)
+	}
+
+	key, err := newKey(passphrase)
+
+	if err != nil {
+		return err
+	}
+
+	s.keys[name] = key
+
+	return s.Save()
+}
+
+// Rekey changes the passphrase of an encrypted list. The current passphrase
+// must be given again so an unattended session can't be used to lock the
+// owner out.
+func (s *Store) Rekey(name, oldPassphrase, newPassphrase string) error {
+	key, err := s.checkPassphrase(name, oldPassphrase)
+
+	if err != nil {
+		return err
+	}
+
+	replacement, err := newKey(newPassphrase)
+
+	if err != nil {
+		return err
+	}
+
+	s.keys[name] = replacement
+
+	if err := s.Save(); err != nil {
+		s.keys[name] = key
+		return err
+	}
+
+	return nil
+}
+
+// Decrypt goes back to storing the named list as plain JSON
+func (s *Store) Decrypt(name, passphrase string) error {
+	key, err := s.checkPassphrase(name, passphrase)
+
+	if err != nil {
+		return err
+	}
+
+	delete(s.keys, name)
+
+	if err := s.Save(); err != nil {
+		s.keys[name] = key
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) checkPassphrase(name, passphrase string) (*cipherKey, error) {
+	if _, err := s.List(name); err != nil {
+		return nil, err
+	}
+
+	key := s.keys[name]
+
+	if key == nil {
+		return nil, fmt.Errorf("list %q is not encrypted", name)
+	}
+
+	if !key.matches(passphrase) {
+		return nil, errors.New("wrong passphrase")
+	}
+
+	return key, nil
+}
+
+// Names returns the names of all known lists in alphabetical order
+func (s *Store) Names() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, listsDir))
+
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{s.current: true}
+
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+
+		if ok && !entry.IsDir() && ValidateName(name) == nil {
+			seen[name] = true
+		}
+	}
+
+	for name := range s.lists {
+		seen[name] = true
+	}
+
+	names := make([]string, 0, len(seen))
+
+	for name := range seen {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Move transfers a todo from the current list to another one and returns
+// its ID in the destination list
+func (s *Store) Move(id int, to string) (int, error) {
+	if to == s.current {
+		return 0, fmt.Errorf("todo is already in list %q", to)
+	}
+
+	from, err := s.List(s.current)
+
+	if err != nil {
+		return 0, err
+	}
+
+	dest, err := s.List(to)
+
+	if err != nil {
+		return 0, err
+	}
+
+	t, exists := from.ExportTodo(id)
+
+	if !exists {
+		return 0, fmt.Errorf("todo with ID %d not found", id)
+	}
+
+	return dest.ImportTodo(t), nil
+}
+
+// Save writes every list that has been loaded back to disk
+func (s *Store) Save() error {
+	for name, tm := range s.lists {
+		data, err := json.MarshalIndent(tm, "", "  ")
+
+		if err != nil {
+			return fmt.Errorf("save list %q: %w", name, err)
+		}
+
+		if key := s.keys[name]; key != nil {
+			if data, err = seal(key, data); err != nil {
+				return fmt.Errorf("save list %q: %w", name, err)
+			}
+		}
+
+		if err := writeFile(s.listPath(name), data); err != nil {
+			return fmt.Errorf("save list %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) listPath(name string) string {
+	return filepath.Join(s.dir, listsDir, name+".json")
+}
+
+// findOverride walks up from the working directory looking for an override file
+func findOverride() (name, path string, err error) {
+	dir, err := os.Getwd()
+
+	if err != nil {
+		return "", "", nil
+	}
+
+	for {
+		path = filepath.Join(dir, OverrideFile)
+		data, err := os.ReadFile(path)
+
+		if err == nil {
+			name = strings.TrimSpace(string(data))
+
+			if err := ValidateName(name); err != nil {
+				return "", "", fmt.Errorf("%s: %w", path, err)
+			}
+
+			return name, path, nil
+		}
+
+		parent := filepath.Dir(dir)
+
+		if parent == dir {
+			return "", "", nil
+		}
+
+		dir = parent
+	}
+}
+
+// writeFile replaces path atomically so a crash never leaves a half-written list
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,208 @@
+package todo
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/neel07sanghvi/todo-cli/internal/dates"
+)
+
+// Priorities in descending order of importance
+var Priorities = []string{"H", "M", "L"}
+
+var tagPattern = regexp.MustCompile(`^[+-][A-Za-z][A-Za-z0-9_-]*$`)
+
+// Modification is a change to a todo's task and attributes, written in the
+// Taskwarrior style:
+//
+//	Buy milk due:friday pri:H +shopping depends:3
+//
+// Words that aren't attributes make up the task text. An empty value
+// (due:, pri:, depends:) clears the attribute and -tag removes a tag.
+type Modification struct {
+	Task       string
+	Due        *time.Time
+	ClearDue   bool
+	Priority   *string
+	AddTags    []string
+	RemoveTags []string
+	Depends    []string // references (IDs or UUID prefixes) as typed
+	SetDepends bool
+}
+
+// ParseModification splits words into task text and attributes
+func ParseModification(words []string, now time.Time) (Modification, error) {
+	var m Modification
+	var task []string
+
+	for _, word := range words {
+		isAttribute, err := m.ParseWord(word, now)
+
+		if err != nil {
+			return m, err
+		}
+
+		if !isAttribute {
+			task = append(task, word)
+		}
+	}
+
+	m.Task = strings.Join(task, " ")
+
+	return m, nil
+}
+
+// ParseWord records word in m if it is an attribute and reports whether it was
+func (m *Modification) ParseWord(word string, now time.Time) (bool, error) {
+	key, value, isAttribute := strings.Cut(word, ":")
+
+	switch {
+	case tagPattern.MatchString(word) && word[0] == '+':
+		m.AddTags = append(m.AddTags, word[1:])
+
+	case tagPattern.MatchString(word):
+		m.RemoveTags = append(m.RemoveTags, word[1:])
+
+	case isAttribute && key == "due":
+		if value == "" {
+			m.ClearDue = true
+			return true, nil
+		}
+
+		due, err := dates.Parse(value, now)
+
+		if err != nil {
+			return true, err
+		}
+
+		m.Due = &due
+
+	case isAttribute && (key == "pri" || key == "priority"):
+		priority := strings.ToUpper(value)
+
+		if priority != "" && !slices.Contains(Priorities, priority) {
+			return true, fmt.Errorf("priority must be H, M or L, got %q", value)
+		}
+
+		m.Priority = &priority
+
+	case isAttribute && (key == "depends" || key == "dep"):
+		m.SetDepends = true
+
+		if value != "" {
+			m.Depends = strings.Split(value, ",")
+		}
+
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// RemovesTag reports whether word is a -tag. It removes a tag when changing
+// a todo or filtering, but in a new todo there is nothing to remove, so
+// callers treat it as text there: "Fix e-mail -urgent" keeps "-urgent".
+func RemovesTag(word string) bool {
+	return tagPattern.MatchString(word) && word[0] == '-'
+}
+
+// apply changes t according to m. Dependencies must already be resolved to UUIDs.
+func (m Modification) apply(t *Todo, depends []string) {
+	if m.Task != "" {
+		t.Task = m.Task
+	}
+
+	if m.ClearDue {
+		t.Due = nil
+	}
+
+	if m.Due != nil {
+		due := *m.Due
+		t.Due = &due
+	}
+
+	if m.Priority != nil {
+		t.Priority = *m.Priority
+	}
+
+	for _, tag := range m.AddTags {
+		if !t.HasTag(tag) {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+
+	t.Tags = slices.DeleteFunc(t.Tags, func(tag string) bool {
+		return slices.Contains(m.RemoveTags, tag)
+	})
+
+	if m.SetDepends {
+		t.Depends = depends
+	}
+}
+
+// Filter selects todos by attribute, using the same syntax as Modification:
+// due:<date> keeps todos due on or before that day, pri:H keeps a priority,
+// +tag requires a tag and -tag excludes it. Todos hidden at Now (snoozed or
+// waiting) are left out unless IncludeHidden is set.
+type Filter struct {
+	DueBy         *time.Time
+	Priority      string
+	Tags          []string
+	ExcludeTags   []string
+	IncludeHidden bool
+	Now           time.Time
+}
+
+// ParseFilter reads filter words such as due:today or +work
+func ParseFilter(words []string, now time.Time) (Filter, error) {
+	m, err := ParseModification(words, now)
+
+	if err != nil {
+		return Filter{}, err
+	}
+
+	if m.Task != "" || m.SetDepends || m.ClearDue {
+		return Filter{}, fmt.Errorf("unknown filter %q (use due:<date>, pri:<H|M|L>, +tag or -tag)", strings.Join(words, " "))
+	}
+
+	f := Filter{DueBy: m.Due, Tags: m.AddTags, ExcludeTags: m.RemoveTags, Now: now}
+
+	if m.Priority != nil {
+		f.Priority = *m.Priority
+	}
+
+	return f, nil
+}
+
+// Match reports whether t passes the filter
+func (f Filter) Match(t *Todo) bool {
+	if !f.IncludeHidden && t.Hidden(f.Now) {
+		return false
+	}
+
+	if f.DueBy != nil && (t.Due == nil || t.Due.After(*f.DueBy)) {
+		return false
+	}
+
+	if f.Priority != "" && t.Priority != f.Priority {
+		return false
+	}
+
+	for _, tag := range f.Tags {
+		if !t.HasTag(tag) {
+			return false
+		}
+	}
+
+	for _, tag := range f.ExcludeTags {
+		if t.HasTag(tag) {
+			return false
+		}
+	}
+
+	return true
+}
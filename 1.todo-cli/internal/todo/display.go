@@ -0,0 +1,97 @@
+package todo
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sort orders understood by SortTodos
+const (
+	SortByID      = "id"
+	SortByCreated = "created"
+	SortByTask    = "task"
+	SortByStatus  = "status"
+	SortByDue     = "due"
+	SortByUrgency = "urgency"
+)
+
+// SortOrders lists every valid sort order
+var SortOrders = []string{SortByID, SortByCreated, SortByTask, SortByStatus, SortByDue, SortByUrgency}
+
+// Display holds the user's display preferences, passed to whatever prints todos
+type Display struct {
+	DateFormat string // Go time layout used when printing timestamps
+	Color      bool
+	SortOrder  string // order used when none is asked for
+}
+
+// DefaultDisplay returns the preferences used without a config file
+func DefaultDisplay() Display {
+	return Display{DateFormat: "2006-01-02 15:04", SortOrder: SortByID}
+}
+
+// ANSI escape codes for Colorize
+const (
+	colorReset  = "\033[0m"
+	ColorGreen  = "\033[32m"
+	ColorYellow = "\033[33m"
+	ColorDim    = "\033[2m"
+	ColorCyan   = "\033[36m"
+	ColorRed    = "\033[31m"
+	ColorBold   = "\033[1m"
+	ColorInvert = "\033[7m"
+)
+
+// IsSortOrder reports whether order is one of SortOrders
+func IsSortOrder(order string) bool {
+	return slices.Contains(SortOrders, order)
+}
+
+// SortTodos orders todos in place. Ties are broken by ID so the output is stable.
+func (tm *TodoManager) SortTodos(todos []*Todo, order string) {
+	if order == SortByUrgency {
+		tm.sortByUrgency(todos, time.Now())
+		return
+	}
+
+	sort.SliceStable(todos, func(i, j int) bool {
+		a, b := todos[i], todos[j]
+
+		switch order {
+		case SortByCreated:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		case SortByTask:
+			if ta, tb := strings.ToLower(a.Task), strings.ToLower(b.Task); ta != tb {
+				return ta < tb
+			}
+		case SortByStatus:
+			// Pending todos first
+			if a.Completed != b.Completed {
+				return !a.Completed
+			}
+		case SortByDue:
+			// Todos without a due date last
+			switch {
+			case a.Due != nil && b.Due != nil && !a.Due.Equal(*b.Due):
+				return a.Due.Before(*b.Due)
+			case (a.Due == nil) != (b.Due == nil):
+				return a.Due != nil
+			}
+		}
+
+		return a.ID < b.ID
+	})
+}
+
+// Colorize wraps s in an ANSI color code when color output is enabled
+func (d Display) Colorize(code, s string) string {
+	if !d.Color {
+		return s
+	}
+
+	return code + s + colorReset
+}
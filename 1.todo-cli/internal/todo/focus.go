@@ -0,0 +1,91 @@
+package todo
+
+import (
+	"sort"
+	"time"
+
+	"github.com/neel07sanghvi/todo-cli/internal/dates"
+)
+
+// Session is a completed pomodoro (focus session) spent on a todo
+type Session struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Duration returns how long the session lasted
+func (s Session) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// RecordSession adds a finished focus session to a todo item
+func (tm *TodoManager) RecordSession(id int, start, end time.Time) error {
+	return tm.modify(id, EventModify, func(todo *Todo) {
+		todo.Sessions = append(todo.Sessions, Session{Start: start, End: end})
+	})
+}
+
+// TaskFocus is the focus time spent on one todo
+type TaskFocus struct {
+	Todo     *Todo
+	Sessions int
+	Total    time.Duration
+}
+
+// DayFocus is the focus time spent on one day
+type DayFocus struct {
+	Day      time.Time
+	Sessions int
+	Total    time.Duration
+}
+
+// FocusStats returns the pomodoros per todo (most first) and per day
+// (oldest first). Days are calendar days in loc.
+func (tm *TodoManager) FocusStats(loc *time.Location) ([]TaskFocus, []DayFocus) {
+	var tasks []TaskFocus
+
+	// Keyed by the day as written in dates.Layout: equal times compare
+	// unequal as map keys in another location or with a monotonic reading
+	days := make(map[string]*DayFocus)
+
+	for _, todo := range tm.GetAllTodos() {
+		if len(todo.Sessions) == 0 {
+			continue
+		}
+
+		task := TaskFocus{Todo: todo}
+
+		for _, s := range todo.Sessions {
+			task.Sessions++
+			task.Total += s.Duration()
+
+			day := dates.Day(s.Start.In(loc))
+			key := day.Format(dates.Layout)
+
+			if days[key] == nil {
+				days[key] = &DayFocus{Day: day}
+			}
+
+			days[key].Sessions++
+			days[key].Total += s.Duration()
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Sessions > tasks[j].Sessions
+	})
+
+	perDay := make([]DayFocus, 0, len(days))
+
+	for _, day := range days {
+		perDay = append(perDay, *day)
+	}
+
+	sort.Slice(perDay, func(i, j int) bool {
+		return perDay[i].Day.Before(perDay[j].Day)
+	})
+
+	return tasks, perDay
+}
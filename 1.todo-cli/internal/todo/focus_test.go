@@ -0,0 +1,60 @@
+package todo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/neel07sanghvi/todo-cli/internal/dates"
+)
+
+func TestFocusStatsPerDay(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	tm := searchList(t, "Write report", "Review code")
+
+	sessions := []struct {
+		id    int
+		start time.Time
+	}{
+		{1, time.Date(2026, 3, 9, 23, 0, 0, 0, time.UTC)}, // already the 10th in loc
+		{2, time.Date(2026, 3, 10, 9, 0, 0, 0, loc)},
+		{1, time.Date(2026, 3, 10, 20, 0, 0, 0, time.UTC)}, // the 10th in loc, just
+		{2, time.Date(2026, 3, 8, 9, 0, 0, 0, loc)},
+		{1, time.Now()}, // with a monotonic clock reading
+	}
+
+	for _, s := range sessions {
+		if err := tm.RecordSession(s.id, s.start, s.start.Add(25*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tasks, days := tm.FocusStats(loc)
+
+	if len(tasks) != 2 || tasks[0].Todo.ID != 1 || tasks[0].Sessions != 3 || tasks[1].Sessions != 2 {
+		t.Errorf("tasks = %+v, want todo 1 with 3 sessions, then todo 2 with 2", tasks)
+	}
+
+	today := time.Now().In(loc)
+	want := []struct {
+		day      string
+		sessions int
+	}{
+		{"2026-03-08", 1},
+		{"2026-03-10", 3},
+		{today.Format(dates.Layout), 1},
+	}
+
+	if len(days) != len(want) {
+		t.Fatalf("got %d days, want %d: %+v", len(days), len(want), days)
+	}
+
+	for i, w := range want {
+		if got := days[i].Day.Format(dates.Layout); got != w.day || days[i].Sessions != w.sessions {
+			t.Errorf("day %d = %s with %d sessions, want %s with %d", i, got, days[i].Sessions, w.day, w.sessions)
+		}
+
+		if days[i].Total != time.Duration(w.sessions)*25*time.Minute {
+			t.Errorf("day %s total %v", w.day, days[i].Total)
+		}
+	}
+}
@@ -1,113 +1,305 @@
 package todo
 
 import (
+	"errors"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// ErrNotFound is returned when a reference matches no todo
+var ErrNotFound = errors.New("no such todo")
+
+// Lifecycle events passed to a HookFunc
+const (
+	EventAdd      = "add"
+	EventModify   = "modify"
+	EventComplete = "complete"
+	EventDelete   = "delete"
+)
+
+// HookFunc is called before a change is committed. old is nil for add and
+// updated is nil for delete. Returning an error rejects the change; the
+// returned todo (if not nil) is stored instead of updated.
+type HookFunc func(event string, old, updated *Todo) (*Todo, error)
+
 // TodoManager manages the collection of todos
 type TodoManager struct {
-	todos  map[int]*Todo
-	nextID int
+	todos   map[int]*Todo
+	nextID  int
+	hook    HookFunc
+	weights UrgencyWeights
 }
 
 // NewTodoManager creates a new TodoManager instance
 func NewTodoManager() *TodoManager {
 	return &TodoManager{
-		todos:  make(map[int]*Todo),
-		nextID: 1,
+		todos:   make(map[int]*Todo),
+		nextID:  1,
+		weights: DefaultUrgencyWeights(),
 	}
 }
 
+// SetHook installs fn to be run before every add, modify, complete and delete
+func (tm *TodoManager) SetHook(fn HookFunc) {
+	tm.hook = fn
+}
+
+// SetUrgencyWeights sets the weights Urgency and the urgency sort order use
+func (tm *TodoManager) SetUrgencyWeights(w UrgencyWeights) {
+	tm.weights = w
+}
+
 // AddTodo adds a new todo item and returns its ID
-func (tm *TodoManager) AddTodo(task string) int {
+func (tm *TodoManager) AddTodo(task string) (int, error) {
+	return tm.AddTodoWith(Modification{Task: task})
+}
+
+// AddTodoWith adds a new todo item with the task and attributes of m and
+// returns its ID
+func (tm *TodoManager) AddTodoWith(m Modification) (int, error) {
+	if m.Task == "" {
+		return 0, errors.New("task description must not be empty")
+	}
+
+	depends, err := tm.resolveDepends(0, m.Depends)
+
+	if err != nil {
+		return 0, err
+	}
+
 	todo := &Todo{
 		ID:        tm.nextID,
-		Task:      task,
+		UUID:      NewUUID(),
 		Completed: false,
 		CreatedAt: time.Now(),
 	}
 
+	m.apply(todo, depends)
+
+	todo, err = tm.runHook(EventAdd, nil, todo)
+
+	if err != nil {
+		return 0, err
+	}
+
 	tm.todos[todo.ID] = todo
 	tm.nextID++
 
-	return todo.ID
+	return todo.ID, nil
 }
 
-// ListTodos displays all todo items
-func (tm *TodoManager) ListTodos() {
-	if len(tm.todos) == 0 {
-		fmt.Println("No todos found. Add some todos to get started!")
-		return
+// ImportTodo adds an existing todo (e.g. one moved from another list),
+// keeping its timestamps and status but giving it a new ID in this list
+func (tm *TodoManager) ImportTodo(todo *Todo) int {
+	todo.ID = tm.nextID
+
+	if todo.UUID == "" {
+		todo.UUID = NewUUID()
 	}
 
-	var ids []int
-	for id := range tm.todos {
-		ids = append(ids, id)
+	tm.todos[todo.ID] = todo
+	tm.nextID++
+
+	return todo.ID
+}
+
+// ExportTodo removes a todo so it can be imported into another list. Unlike
+// DeleteTodo it doesn't run the delete hook, since the todo lives on.
+func (tm *TodoManager) ExportTodo(id int) (*Todo, bool) {
+	todo, exists := tm.todos[id]
+
+	if exists {
+		delete(tm.todos, id)
 	}
 
-	sort.Ints(ids)
+	return todo, exists
+}
 
-	fmt.Println("\n=== Your Todos ===")
+// ListTodos returns the todo items that pass filter, sorted by order (by ID
+// when order is empty)
+func (tm *TodoManager) ListTodos(order string, filter Filter) []*Todo {
+	var todos []*Todo
 
-	for _, id := range ids {
-		fmt.Println(tm.todos[id].String())
+	for _, todo := range tm.GetAllTodos() {
+		if filter.Match(todo) {
+			todos = append(todos, todo)
+		}
 	}
 
-	completed := tm.GetCompletedCount()
-	total := len(tm.todos)
+	tm.SortTodos(todos, order)
 
-	fmt.Printf("\nTotal: %d | Completed: %d | Remaining: %d\n", total, completed, total-completed)
+	return todos
 }
 
 // UpdateTodo updates an existing todo item
-func (tm *TodoManager) UpdateTodo(id int, newTask string) bool {
-	todo, exists := tm.todos[id]
+func (tm *TodoManager) UpdateTodo(id int, newTask string) error {
+	return tm.modify(id, EventModify, func(todo *Todo) {
+		todo.Task = newTask
+	})
+}
 
-	if !exists {
-		return false
+// ModifyTodo changes the task and attributes of a todo item
+func (tm *TodoManager) ModifyTodo(id int, m Modification) error {
+	depends, err := tm.resolveDepends(id, m.Depends)
+
+	if err != nil {
+		return err
 	}
 
-	todo.Task = newTask
+	return tm.modify(id, EventModify, func(todo *Todo) {
+		m.apply(todo, depends)
+	})
+}
+
+// resolveDepends turns dependency references into UUIDs
+func (tm *TodoManager) resolveDepends(id int, refs []string) ([]string, error) {
+	var uuids []string
+
+	for _, ref := range refs {
+		depID, err := tm.Resolve(ref)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if depID == id {
+			return nil, errors.New("a todo can't depend on itself")
+		}
 
-	return true
+		uuids = append(uuids, tm.todos[depID].UUID)
+	}
+
+	return uuids, nil
 }
 
 // DeleteTodo removes a todo item
-func (tm *TodoManager) DeleteTodo(id int) bool {
-	_, exists := tm.todos[id]
+func (tm *TodoManager) DeleteTodo(id int) error {
+	todo, exists := tm.todos[id]
 
 	if !exists {
-		return false
+		return notFound(id)
+	}
+
+	if _, err := tm.runHook(EventDelete, todo, nil); err != nil {
+		return err
 	}
 
 	delete(tm.todos, id)
 
-	return true
+	return nil
 }
 
 // CompleteTodo marks a todo as completed
-func (tm *TodoManager) CompleteTodo(id int) bool {
-	todo, exists := tm.todos[id]
+func (tm *TodoManager) CompleteTodo(id int) error {
+	return tm.modify(id, EventComplete, (*Todo).MarkCompleted)
+}
+
+// IncompleteTodo marks a todo as incomplete
+func (tm *TodoManager) IncompleteTodo(id int) error {
+	return tm.modify(id, EventModify, (*Todo).MarkIncomplete)
+}
+
+// AnnotateTodo appends a timestamped annotation to a todo item
+func (tm *TodoManager) AnnotateTodo(id int, text string) error {
+	return tm.modify(id, EventModify, func(todo *Todo) {
+		todo.Annotate(text)
+	})
+}
+
+// SetNote replaces the Markdown note attached to a todo item
+func (tm *TodoManager) SetNote(id int, note string) error {
+	return tm.modify(id, EventModify, func(todo *Todo) {
+		todo.Note = note
+	})
+}
+
+// modify applies change to a copy of the todo and, once the hook has
+// accepted it, replaces the stored todo with the copy. A rejected change
+// therefore leaves the todo untouched.
+func (tm *TodoManager) modify(id int, event string, change func(*Todo)) error {
+	old, exists := tm.todos[id]
+
 	if !exists {
-		return false
+		return notFound(id)
 	}
 
-	todo.MarkCompleted()
+	updated := old.Clone()
+	change(updated)
 
-	return true
+	updated, err := tm.runHook(event, old, updated)
+
+	if err != nil {
+		return err
+	}
+
+	tm.todos[id] = updated
+
+	return nil
 }
 
-// IncompleteTodo marks a todo as incomplete
-func (tm *TodoManager) IncompleteTodo(id int) bool {
-	todo, exists := tm.todos[id]
-	if !exists {
-		return false
+// runHook passes a change through the hook, if any. Hooks may rewrite the
+// todo but not its identity.
+func (tm *TodoManager) runHook(event string, old, updated *Todo) (*Todo, error) {
+	if tm.hook == nil {
+		return updated, nil
+	}
+
+	result, err := tm.hook(event, old, updated)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || updated == nil {
+		return updated, nil
 	}
 
-	todo.MarkIncomplete()
-	return true
+	result.ID = updated.ID
+	result.UUID = updated.UUID
+
+	return result, nil
+}
+
+func notFound(id int) error {
+	return fmt.Errorf("%w with ID %d", ErrNotFound, id)
+}
+
+// Resolve turns a reference typed by the user into a todo ID. A number is
+// looked up as a short ID first; anything else (or a number that isn't a
+// short ID) must be a prefix of exactly one todo's UUID.
+func (tm *TodoManager) Resolve(ref string) (int, error) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+
+	if ref == "" {
+		return 0, fmt.Errorf("empty todo reference")
+	}
+
+	if id, err := strconv.Atoi(ref); err == nil {
+		if _, exists := tm.todos[id]; exists {
+			return id, nil
+		}
+	}
+
+	var matches []int
+
+	for id, todo := range tm.todos {
+		if strings.HasPrefix(todo.UUID, ref) {
+			matches = append(matches, id)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("%w with ID or UUID prefix %q", ErrNotFound, ref)
+	case 1:
+		return matches[0], nil
+	default:
+		sort.Ints(matches)
+		return 0, fmt.Errorf("UUID prefix %q is ambiguous: it matches todos %v", ref, matches)
+	}
 }
 
 // GetTodo retrieves a specific todo by ID
@@ -2,12 +2,23 @@ package todo
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/neel07sanghvi/todo-cli/internal/dates"
 )
 
+// Annotation is a timestamped remark appended to a todo
+type Annotation struct {
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+}
+
 // Todo represents a single todo item
 type Todo struct {
-	ID          int        `json:"id"`
+	ID          int        `json:"id"`   // short number, only unique within its list
+	UUID        string     `json:"uuid"` // globally unique, stays the same when moved between lists
 	Task        string     `json:"task"`
 	Completed   bool       `json:"completed"`
 	CreatedAt   time.Time  `json:"created_at"`
@@ -22,27 +33,65 @@ type Todo struct {
 
 	// With pointer - clear semantics
 	// CompletedAt *time.Time // nil = not completed, non-nil = completed at specific time
+
+	Annotations []Annotation `json:"annotations,omitempty"`
+	Note        string       `json:"note,omitempty"` // Markdown, edited with the note command
+
+	Due      *time.Time `json:"due,omitempty"`      // midnight of the day the todo is due
+	Priority string     `json:"priority,omitempty"` // H, M, L or empty
+	Tags     []string   `json:"tags,omitempty"`
+	Depends  []string   `json:"depends,omitempty"` // UUIDs of todos that must be completed first
+
+	Sessions []Session `json:"sessions,omitempty"` // pomodoros spent on the todo
+
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"` // hidden from listings before this day
+	Waiting      bool       `json:"waiting,omitempty"`       // blocked on someone else
+	WaitingFor   string     `json:"waiting_for,omitempty"`   // who or what it is waiting for
+	FollowUp     *time.Time `json:"follow_up,omitempty"`     // when to check on a waiting todo again
 }
 
-// String returns a formatted string representation of the todo
+// String returns a formatted string representation of the todo, with the
+// default display preferences
 func (t *Todo) String() string {
-	status := "[x]"
+	return t.Format(DefaultDisplay())
+}
+
+// Format returns the todo as a line of the list, as d says to display it
+func (t *Todo) Format(d Display) string {
+	status := d.Colorize(ColorYellow, "[x]")
 
 	if t.Completed {
-		status = "[✓]"
+		status = d.Colorize(ColorGreen, "[✓]")
 	}
 
 	completedInfo := ""
 
 	if t.Completed && t.CompletedAt != nil {
-		completedInfo = fmt.Sprintf(" (completed: %s)", t.CompletedAt.Format("2006-01-02 15:04"))
+		completedInfo = fmt.Sprintf(" (completed: %s)", t.CompletedAt.Format(d.DateFormat))
 	}
 
-	return fmt.Sprintf("%d. %s %s (created: %s)%s", t.ID, status, t.Task, t.CreatedAt.Format("2006-01-02 15:04"), completedInfo)
+	attributes := ""
+
+	for _, tag := range t.Tags {
+		attributes += " +" + tag
+	}
+
+	if t.Priority != "" {
+		attributes += " pri:" + t.Priority
+	}
+
+	if t.Due != nil {
+		attributes += " due:" + t.Due.Format(dates.Layout)
+	}
+
+	timestamps := d.Colorize(ColorDim, fmt.Sprintf("(created: %s)%s", t.CreatedAt.Format(d.DateFormat), completedInfo))
+
+	return fmt.Sprintf("%d. %s %s%s%s %s", t.ID, status, t.Task, d.Colorize(ColorCyan, attributes), t.stateLabel(time.Now(), d), timestamps)
 }
 
 // MarkCompleted marks the todo as completed
 func (t *Todo) MarkCompleted() {
+	t.Resume()
 	t.Completed = true
 	now := time.Now()
 	t.CompletedAt = &now
@@ -73,3 +122,68 @@ func (t *Todo) MarkIncomplete() {
 	t.Completed = false
 	t.CompletedAt = nil
 }
+
+// Clone returns a deep copy of the todo
+func (t *Todo) Clone() *Todo {
+	clone := *t
+
+	if t.CompletedAt != nil {
+		completedAt := *t.CompletedAt
+		clone.CompletedAt = &completedAt
+	}
+
+	if t.Due != nil {
+		due := *t.Due
+		clone.Due = &due
+	}
+
+	if t.SnoozedUntil != nil {
+		until := *t.SnoozedUntil
+		clone.SnoozedUntil = &until
+	}
+
+	if t.FollowUp != nil {
+		followUp := *t.FollowUp
+		clone.FollowUp = &followUp
+	}
+
+	clone.Annotations = slices.Clone(t.Annotations)
+	clone.Tags = slices.Clone(t.Tags)
+	clone.Depends = slices.Clone(t.Depends)
+	clone.Sessions = slices.Clone(t.Sessions)
+
+	return &clone
+}
+
+// Annotate appends a timestamped annotation to the todo
+func (t *Todo) Annotate(text string) {
+	t.Annotations = append(t.Annotations, Annotation{Time: time.Now(), Text: text})
+}
+
+// Details returns the todo together with its annotations and note
+func (t *Todo) Details(d Display) string {
+	var b strings.Builder
+
+	b.WriteString(t.Format(d))
+	b.WriteString("\nUUID: " + t.UUID)
+
+	if len(t.Annotations) > 0 {
+		b.WriteString("\n\nAnnotations:")
+
+		for _, a := range t.Annotations {
+			fmt.Fprintf(&b, "\n  %s  %s", a.Time.Format(d.DateFormat), a.Text)
+		}
+	}
+
+	if t.Note != "" {
+		b.WriteString("\n\nNote:\n")
+		b.WriteString(t.Note)
+	}
+
+	return b.String()
+}
+
+// HasTag reports whether the todo carries tag
+func (t *Todo) HasTag(tag string) bool {
+	return slices.Contains(t.Tags, tag)
+}
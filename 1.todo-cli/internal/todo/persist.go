@@ -0,0 +1,54 @@
+package todo
+
+import (
+	"encoding/json"
+)
+
+// listFile is the on-disk layout of a single todo list
+type listFile struct {
+	NextID int     `json:"next_id"`
+	Todos  []*Todo `json:"todos"`
+}
+
+// MarshalJSON encodes the manager so it can be written to disk
+func (tm *TodoManager) MarshalJSON() ([]byte, error) {
+	todos := tm.GetAllTodos()
+
+	if todos == nil {
+		todos = []*Todo{}
+	}
+
+	return json.Marshal(listFile{NextID: tm.nextID, Todos: todos})
+}
+
+// UnmarshalJSON restores a manager previously encoded with MarshalJSON
+func (tm *TodoManager) UnmarshalJSON(data []byte) error {
+	var file listFile
+
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+
+	tm.todos = make(map[int]*Todo, len(file.Todos))
+	tm.nextID = 1
+
+	for _, todo := range file.Todos {
+		// Lists saved before todos had UUIDs get one on first load
+		if todo.UUID == "" {
+			todo.UUID = NewUUID()
+		}
+
+		tm.todos[todo.ID] = todo
+
+		if todo.ID >= tm.nextID {
+			tm.nextID = todo.ID + 1
+		}
+	}
+
+	// Never hand out an ID lower than one already used, even if it was deleted
+	if file.NextID > tm.nextID {
+		tm.nextID = file.NextID
+	}
+
+	return nil
+}
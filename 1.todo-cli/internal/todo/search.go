@@ -0,0 +1,535 @@
+package todo
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Fields a search looks in, with how much a match in each counts
+const (
+	FieldTask       = "task"
+	FieldTag        = "tag"
+	FieldAnnotation = "annotation"
+	FieldNote       = "note"
+)
+
+var fieldWeights = map[string]float64{
+	FieldTask:       1.0,
+	FieldTag:        0.9,
+	FieldAnnotation: 0.6,
+	FieldNote:       0.5,
+}
+
+// SearchOptions changes how Search matches
+type SearchOptions struct {
+	CaseSensitive    bool
+	Regex            bool // the query is a regular expression instead of words
+	IncludeCompleted bool
+}
+
+// Span is a matched byte range [Start, End) of a text
+type Span struct {
+	Start, End int
+}
+
+// FieldMatch is the text of one field of a todo with the parts that matched
+type FieldMatch struct {
+	Field string
+	Text  string
+	Spans []Span
+}
+
+// SearchResult is a todo found by Search. Score is higher for better matches.
+type SearchResult struct {
+	Todo    *Todo
+	Score   float64
+	Matches []FieldMatch
+}
+
+// Search finds the todos matching query, best matches first. Every word of
+// the query must be found in the task, tags, annotations or note, but may
+// be misspelt: a word of four letters or more matches a word within one
+// edit (two from eight letters), and a prefix of a longer word counts too.
+// With opts.Regex the whole query is a regular expression instead.
+func (tm *TodoManager) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+	var m matcher
+
+	if opts.Regex {
+		re, err := regexp.Compile(query)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+
+		if !opts.CaseSensitive {
+			re = regexp.MustCompile("(?i)" + query)
+		}
+
+		m.re = re
+	} else {
+		m.caseSensitive = opts.CaseSensitive
+
+		var words []string
+
+		for _, word := range strings.Fields(query) {
+			word = m.fold(word)
+			words = append(words, word)
+			m.terms = append(m.terms, &term{
+				text:   word,
+				runes:  []rune(word),
+				edits:  maxEdits(utf8.RuneCountInString(word)),
+				scores: make(map[string]float64),
+			})
+		}
+
+		if len(m.terms) == 0 {
+			return nil, fmt.Errorf("nothing to search for")
+		}
+
+		m.phrase = strings.Join(words, " ")
+	}
+
+	var results []SearchResult
+
+	for _, t := range tm.todos {
+		if t.Completed && !opts.IncludeCompleted {
+			continue
+		}
+
+		if result, ok := m.match(t); ok {
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+
+		return results[i].Todo.ID < results[j].Todo.ID
+	})
+
+	return results, nil
+}
+
+// Highlight marks the spans of text, in color or else with [brackets]
+func (d Display) Highlight(text string, spans []Span) string {
+	var b strings.Builder
+	last := 0
+
+	for _, span := range spans {
+		if span.Start < last {
+			continue // overlaps the previous span
+		}
+
+		b.WriteString(text[last:span.Start])
+
+		if d.Color {
+			b.WriteString(d.Colorize(ColorBold+ColorYellow, text[span.Start:span.End]))
+		} else {
+			b.WriteString("[" + text[span.Start:span.End] + "]")
+		}
+
+		last = span.End
+	}
+
+	b.WriteString(text[last:])
+
+	return b.String()
+}
+
+// matcher holds a compiled query and the scratch space for edit distances,
+// so searching a large list doesn't allocate per word
+type matcher struct {
+	re            *regexp.Regexp
+	terms         []*term
+	phrase        string // the folded terms joined by spaces
+	caseSensitive bool
+	word          []rune
+	rows          [3][]int
+}
+
+// term is one word of the query
+type term struct {
+	text  string // folded
+	runes []rune
+	edits int
+	// Words tend to repeat across todos, so the typo score of each word
+	// is remembered instead of computing its edit distance every time
+	scores map[string]float64
+}
+
+type field struct {
+	name   string
+	text   string
+	folded string
+	// offsets holds the byte of text that each byte of folded comes from,
+	// and len(text) at the end; nil when folding kept every letter's length
+	offsets []int
+}
+
+func fields(t *Todo) []field {
+	list := []field{{name: FieldTask, text: t.Task}}
+
+	for _, tag := range t.Tags {
+		list = append(list, field{name: FieldTag, text: tag})
+	}
+
+	for _, a := range t.Annotations {
+		list = append(list, field{name: FieldAnnotation, text: a.Text})
+	}
+
+	if t.Note != "" {
+		list = append(list, field{name: FieldNote, text: t.Note})
+	}
+
+	return list
+}
+
+func (m *matcher) fold(s string) string {
+	if m.caseSensitive {
+		return s
+	}
+
+	return strings.ToLower(s)
+}
+
+// foldField fills in f.folded and, where folding changes the length of a
+// letter as with İ or the Kelvin sign, f.offsets
+func (m *matcher) foldField(f *field) {
+	f.folded = m.fold(f.text)
+
+	if m.caseSensitive || len(f.folded) == len(f.text) && isASCII(f.text) {
+		return
+	}
+
+	// Fold again a letter at a time, as strings.ToLower does beyond ASCII,
+	// noting where each byte came from
+	var b strings.Builder
+	offsets := make([]int, 0, len(f.text)+1)
+	changed := false
+
+	for i, r := range f.text {
+		lower := unicode.ToLower(r)
+		size := utf8.RuneLen(lower)
+
+		if size != utf8.RuneLen(r) {
+			changed = true
+		}
+
+		for range size {
+			offsets = append(offsets, i)
+		}
+
+		b.WriteRune(lower)
+	}
+
+	if changed {
+		f.folded = b.String()
+		f.offsets = append(offsets, len(f.text))
+	}
+}
+
+// span turns a span of f.folded into the same span of f.text
+func (f field) span(s Span) Span {
+	if f.offsets == nil {
+		return s
+	}
+
+	return Span{f.offsets[s.Start], f.offsets[s.End]}
+}
+
+func (m *matcher) match(t *Todo) (SearchResult, bool) {
+	if m.re != nil {
+		return m.matchRegex(t)
+	}
+
+	result := SearchResult{Todo: t}
+	all := fields(t)
+	spans := make([][]Span, len(all))
+
+	for i := range all {
+		m.foldField(&all[i])
+	}
+
+	for _, term := range m.terms {
+		best := 0.0
+
+		for i, f := range all {
+			score, span := m.findTerm(f, term)
+
+			if score == 0 {
+				continue
+			}
+
+			spans[i] = append(spans[i], span)
+			best = max(best, score*fieldWeights[f.name])
+		}
+
+		if best == 0 {
+			return SearchResult{}, false
+		}
+
+		result.Score += best
+	}
+
+	result.Score /= float64(len(m.terms))
+
+	// Prefer todos whose task has the query as typed
+	if len(m.terms) > 1 && strings.Contains(all[0].folded, m.phrase) {
+		result.Score += 0.5
+	}
+
+	for i, f := range all {
+		if len(spans[i]) > 0 {
+			sort.Slice(spans[i], func(a, b int) bool { return spans[i][a].Start < spans[i][b].Start })
+			result.Matches = append(result.Matches, FieldMatch{Field: f.name, Text: f.text, Spans: spans[i]})
+		}
+	}
+
+	return result, true
+}
+
+func (m *matcher) matchRegex(t *Todo) (SearchResult, bool) {
+	result := SearchResult{Todo: t}
+
+	for _, f := range fields(t) {
+		match := FieldMatch{Field: f.name, Text: f.text}
+
+		for _, loc := range m.re.FindAllStringIndex(f.text, -1) {
+			// A pattern such as a* matches the empty string anywhere; only
+			// text that was actually matched counts
+			if loc[0] < loc[1] {
+				match.Spans = append(match.Spans, Span{loc[0], loc[1]})
+			}
+		}
+
+		if len(match.Spans) == 0 {
+			continue
+		}
+
+		result.Matches = append(result.Matches, match)
+		result.Score = max(result.Score, fieldWeights[f.name]+0.01*float64(min(len(match.Spans), 10)))
+	}
+
+	return result, len(result.Matches) > 0
+}
+
+// findTerm looks for term in a field and scores the best match: 1 for a
+// whole word, 0.9 for the start of a word, 0.7 inside a word and less for
+// words that are only close to the term. It returns 0 if nothing matches.
+func (m *matcher) findTerm(f field, t *term) (float64, Span) {
+	text := f.folded
+
+	if score, span := findSubstring(text, t.text); score > 0 {
+		return score, f.span(span)
+	}
+
+	if t.edits == 0 {
+		return 0, Span{}
+	}
+
+	bestScore, bestSpan := 0.0, Span{}
+
+	for start := 0; start < len(text); {
+		r, size := utf8.DecodeRuneInString(text[start:])
+
+		if !isWordRune(r) {
+			start += size
+			continue
+		}
+
+		end := start
+
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+
+			if !isWordRune(r) {
+				break
+			}
+
+			end += size
+		}
+
+		if score := m.scoreWord(text[start:end], t); score > bestScore {
+			bestScore, bestSpan = score, Span{start, end}
+		}
+
+		start = end
+	}
+
+	return bestScore, f.span(bestSpan)
+}
+
+func findSubstring(text, term string) (float64, Span) {
+	best, bestSpan := 0.0, Span{}
+
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], term)
+
+		if i < 0 {
+			return best, bestSpan
+		}
+
+		start, end := offset+i, offset+i+len(term)
+		score := 0.7
+
+		if startsWord(text, start) {
+			score = 0.9
+
+			if endsWord(text, end) {
+				return 1, Span{start, end}
+			}
+		}
+
+		if score > best {
+			best, bestSpan = score, Span{start, end}
+		}
+
+		offset = start + 1
+	}
+}
+
+// scoreWord compares a folded word of the text with a term that wasn't
+// found verbatim, allowing up to t.edits typos; a misspelt prefix of a
+// longer word scores a little lower than a misspelt whole word
+func (m *matcher) scoreWord(word string, t *term) float64 {
+	// A word can't have more letters than bytes, so this skips short words
+	// without a lookup
+	if len(word)+t.edits < len(t.runes) {
+		return 0
+	}
+
+	if score, ok := t.scores[word]; ok {
+		return score
+	}
+
+	score := 0.0
+
+	if utf8.RuneCountInString(word)+t.edits >= len(t.runes) {
+		m.word = append(m.word[:0], []rune(word)...)
+		limit := float64(t.edits + 1)
+
+		if len(m.word) <= len(t.runes)+t.edits {
+			if d := m.distance(m.word, t.runes, t.edits); d <= t.edits {
+				score = 0.6 * (1 - float64(d)/limit)
+			}
+		}
+
+		if len(m.word) > len(t.runes) {
+			if d := m.distance(m.word[:len(t.runes)], t.runes, t.edits); d <= t.edits {
+				score = max(score, 0.5*(1-float64(d)/limit))
+			}
+		}
+	}
+
+	t.scores[word] = score
+
+	return score
+}
+
+// distance returns the optimal string alignment distance between a and b
+// (edits being insertions, deletions, substitutions and swaps of adjacent
+// letters), or limit+1 as soon as it is known to be more than limit
+func (m *matcher) distance(a, b []rune, limit int) int {
+	if abs(len(a)-len(b)) > limit {
+		return limit + 1
+	}
+
+	for i := range m.rows {
+		if cap(m.rows[i]) < len(b)+1 {
+			m.rows[i] = make([]int, len(b)+1)
+		}
+
+		m.rows[i] = m.rows[i][:len(b)+1]
+	}
+
+	prevprev, prev, cur := m.rows[0], m.rows[1], m.rows[2]
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prevprev[j-2]+1)
+			}
+
+			rowMin = min(rowMin, cur[j])
+		}
+
+		if rowMin > limit {
+			return limit + 1
+		}
+
+		prevprev, prev, cur = prev, cur, prevprev
+	}
+
+	return prev[len(b)]
+}
+
+// maxEdits is how many typos a term of n letters tolerates
+func maxEdits(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func isWordRune(r rune) bool {
+	if r < utf8.RuneSelf {
+		return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9'
+	}
+
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// startsWord reports whether byte i of text isn't in the middle of a word
+func startsWord(text string, i int) bool {
+	before, _ := utf8.DecodeLastRuneInString(text[:i])
+	return i == 0 || !isWordRune(before)
+}
+
+// endsWord reports whether a word can't continue past byte i of text
+func endsWord(text string, i int) bool {
+	after, _ := utf8.DecodeRuneInString(text[i:])
+	return i == len(text) || !isWordRune(after)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,365 @@
+package todo
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// searchList makes a list with a todo per task, numbered from 1 in order
+func searchList(t testing.TB, tasks ...string) *TodoManager {
+	t.Helper()
+
+	tm := NewTodoManager()
+
+	for _, task := range tasks {
+		if _, err := tm.AddTodo(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return tm
+}
+
+func resultIDs(results []SearchResult) []int {
+	var ids []int
+
+	for _, r := range results {
+		ids = append(ids, r.Todo.ID)
+	}
+
+	return ids
+}
+
+func TestSearchTypos(t *testing.T) {
+	tasks := []string{
+		"Buy groceries",
+		"Call the plumber",
+		"Prepare quarterly report",
+		"Fix bug in parser",
+		"Renew passport",
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"groceries", []int{1}},
+		{"grocereis", []int{1}},        // swapped letters
+		{"plumbr", []int{2}},           // a letter missing
+		{"quartrely reprot", []int{3}}, // a typo in each word
+		{"quarterlyy", []int{3}},
+		{"qarterly", []int{3}},
+		{"passprt", []int{5}},
+		{"pars", []int{4, 5}},   // prefix of parser, and one edit from the start of passport
+		{"bgu", nil},            // short words must match exactly
+		{"bug", []int{4}},       // three letters, exact
+		{"groxxries", []int{1}}, // two edits in a word of nine letters is the limit
+		{"grxxxries", nil},      // three is too many
+		{"renew report", nil},   // every word must match the same todo
+		{"PLUMBER", []int{2}},   // case-insensitive by default
+		{"Prepare report", []int{3}},
+	}
+
+	tm := searchList(t, tasks...)
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results, err := tm.Search(tt.query, SearchOptions{})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := resultIDs(results); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchEditLimits(t *testing.T) {
+	tests := []struct {
+		task  string
+		query string
+		match bool
+	}{
+		{"cat", "cta", false},          // under four letters: no typos
+		{"milk", "mlik", true},         // four to seven letters: one edit
+		{"milk", "mikl", true},         // a swap is one edit
+		{"milk", "mxlx", false},        // two edits are too many
+		{"tomorrow", "tommorow", true}, // eight or more letters: two edits
+		{"tomorrow", "tamorrww", true}, // two substitutions
+		{"tomorrow", "tmorrow", true},  // the query's length sets the limit: one edit for seven letters
+		{"tomorrow", "tmorrw", false},
+		{"tomorrow", "txmxrxow", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.task+"/"+tt.query, func(t *testing.T) {
+			results, err := searchList(t, tt.task).Search(tt.query, SearchOptions{})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := len(results) == 1; got != tt.match {
+				t.Errorf("Search(%q) in %q matched = %v, want %v", tt.query, tt.task, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(tm *TodoManager)
+		query string
+		want  []int
+	}{
+		{
+			name: "whole word before start of word before inside a word before typo",
+			setup: func(tm *TodoManager) {
+				tm.AddTodo("Repaint the fence")   // 1: inside a word
+				tm.AddTodo("Paint the fence")     // 2: whole word
+				tm.AddTodo("Paintball on Sunday") // 3: start of a word
+				tm.AddTodo("Pant the fence")      // 4: one edit
+			},
+			query: "paint",
+			want:  []int{2, 3, 1, 4},
+		},
+		{
+			name: "task before tag before annotation before note",
+			setup: func(tm *TodoManager) {
+				tm.AddTodo("Call Alice")                                                    // 1: note
+				tm.AddTodoWith(Modification{Task: "Call Bob", AddTags: []string{"garden"}}) // 2: tag
+				tm.AddTodo("Call Carol")                                                    // 3: annotation
+				tm.AddTodo("Weed the garden")                                               // 4: task
+				tm.SetNote(1, "ask about the garden")
+				tm.AnnotateTodo(3, "garden party")
+			},
+			query: "garden",
+			want:  []int{4, 2, 3, 1},
+		},
+		{
+			name: "the phrase as typed wins",
+			setup: func(tm *TodoManager) {
+				tm.AddTodo("Report the quarterly numbers") // 1: both words, apart
+				tm.AddTodo("Quarterly report")             // 2: the phrase
+			},
+			query: "quarterly report",
+			want:  []int{2, 1},
+		},
+		{
+			name: "ties are broken by ID",
+			setup: func(tm *TodoManager) {
+				tm.AddTodo("Email Dana")
+				tm.AddTodo("Email Eve")
+				tm.AddTodo("Email Frank")
+			},
+			query: "email",
+			want:  []int{1, 2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := NewTodoManager()
+			tt.setup(tm)
+
+			results, err := tm.Search(tt.query, SearchOptions{})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := resultIDs(results); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchOptions(t *testing.T) {
+	tm := searchList(t, "Fix issue #12 in the API", "Write API docs", "Refactor api client", "fix flaky test")
+	tm.CompleteTodo(4)
+
+	tests := []struct {
+		name    string
+		query   string
+		opts    SearchOptions
+		want    []int
+		wantErr bool
+	}{
+		{name: "regex", query: `#\d+`, opts: SearchOptions{Regex: true}, want: []int{1}},
+		{name: "regex ignores case by default", query: `^(write|refactor)`, opts: SearchOptions{Regex: true}, want: []int{2, 3}},
+		{name: "regex with case", query: `API`, opts: SearchOptions{Regex: true, CaseSensitive: true}, want: []int{1, 2}},
+		{name: "regex matching nothing but the empty string", query: `z*`, opts: SearchOptions{Regex: true}, want: nil},
+		{name: "regex that may match the empty string", query: `x?`, opts: SearchOptions{Regex: true}, want: []int{1}},
+		{name: "regex anchor alone", query: `^`, opts: SearchOptions{Regex: true}, want: nil},
+		{name: "invalid regex", query: `(`, opts: SearchOptions{Regex: true}, wantErr: true},
+		{name: "case-sensitive words", query: "api", opts: SearchOptions{CaseSensitive: true}, want: []int{3}},
+		{name: "completed left out", query: "fix", want: []int{1}},
+		{name: "completed included", query: "fix", opts: SearchOptions{IncludeCompleted: true}, want: []int{1, 4}},
+		{name: "empty query", query: "  ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := tm.Search(tt.query, tt.opts)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Search(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			}
+
+			if got := resultIDs(results); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchSpans(t *testing.T) {
+	tests := []struct {
+		name  string
+		task  string
+		query string
+		opts  SearchOptions
+		want  string // the task highlighted with brackets
+	}{
+		{name: "whole word", task: "Buy milk and eggs", query: "milk", want: "Buy [milk] and eggs"},
+		{name: "several words", task: "Buy milk and eggs", query: "eggs milk", want: "Buy [milk] and [eggs]"},
+		{name: "typo marks the word", task: "Call the plumber", query: "plumbr", want: "Call the [plumber]"},
+		{name: "prefix", task: "Fix parser", query: "pars", want: "Fix [pars]er"},
+		{name: "regex marks every match", task: "a1 b22 c333", query: `\d+`, opts: SearchOptions{Regex: true}, want: "a[1] b[22] c[333]"},
+		{name: "regex skips empty matches", task: "a1 b22 c333", query: `\d*`, opts: SearchOptions{Regex: true}, want: "a[1] b[22] c[333]"},
+		{name: "case folded", task: "Ünïcode Straße", query: "straße", want: "Ünïcode [Straße]"},
+		{name: "folding that changes length", task: "İstanbul trip", query: "trip", want: "İstanbul [trip]"},
+		{name: "short word after folding that changes length", task: "İstanbul bug fix", query: "bug", want: "İstanbul [bug] fix"},
+		{name: "the letter that changes length", task: "İstanbul bug fix", query: "istanbul", want: "[İstanbul] bug fix"},
+		{name: "typo after folding that changes length", task: "İstanbul bug fix", query: "istanbol", want: "[İstanbul] bug fix"},
+		{name: "prefix after folding that changes length", task: "Visit İstanbul", query: "ista", want: "Visit [İsta]nbul"},
+		{name: "Kelvin sign", task: "Cool to 4 \u212a then fix", query: "k then", want: "Cool to 4 [\u212a] [then] fix"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := searchList(t, tt.task).Search(tt.query, tt.opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(results) != 1 || len(results[0].Matches) == 0 {
+				t.Fatalf("Search(%q) = %+v, want the task to match", tt.query, results)
+			}
+
+			match := results[0].Matches[0]
+
+			if match.Field != FieldTask {
+				t.Fatalf("matched field %q, want %q", match.Field, FieldTask)
+			}
+
+			if got := (Display{}).Highlight(match.Text, match.Spans); got != tt.want {
+				t.Errorf("highlighted %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		color bool
+		spans []Span
+		want  string
+	}{
+		{name: "none", want: "hello world"},
+		{name: "brackets", spans: []Span{{0, 5}}, want: "[hello] world"},
+		{name: "overlap skipped", spans: []Span{{0, 5}, {3, 8}, {6, 11}}, want: "[hello] [world]"},
+		{name: "color", color: true, spans: []Span{{6, 11}}, want: "hello " + ColorBold + ColorYellow + "world" + colorReset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Display{Color: tt.color}).Highlight("hello world", tt.spans); got != tt.want {
+				t.Errorf("Highlight = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"milk", "milk", 2, 0},
+		{"milk", "mlik", 2, 1},
+		{"milk", "silk", 2, 1},
+		{"milk", "mil", 2, 1},
+		{"kitten", "sitting", 3, 3},
+		{"kitten", "sitting", 1, 2}, // cut short at limit+1
+		{"ab", "abcdef", 2, 3},
+		{"", "abc", 3, 3},
+	}
+
+	var m matcher
+
+	for _, tt := range tests {
+		if got := m.distance([]rune(tt.a), []rune(tt.b), tt.limit); got != tt.want {
+			t.Errorf("distance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
+
+// BenchmarkSearch searches a list of 50,000 todos built from a small
+// vocabulary, as real lists repeat their words
+func BenchmarkSearch(b *testing.B) {
+	words := strings.Fields("buy call email fix write review plan book clean pay order update " +
+		"report invoice meeting dentist groceries plumber parser release budget quarterly " +
+		"presentation garden birthday passport insurance subscription backup server")
+	rng := rand.New(rand.NewPCG(1, 2))
+	tm := NewTodoManager()
+
+	for i := range 50_000 {
+		task := make([]string, 3+rng.IntN(5))
+
+		for j := range task {
+			task[j] = words[rng.IntN(len(words))]
+		}
+
+		id, err := tm.AddTodo(strings.Join(task, " "))
+
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if i%10 == 0 {
+			tm.AnnotateTodo(id, fmt.Sprintf("note %d about the %s", i, words[rng.IntN(len(words))]))
+		}
+	}
+
+	queries := []struct {
+		name  string
+		query string
+		opts  SearchOptions
+	}{
+		{"exact", "invoice", SearchOptions{}},
+		{"typo", "quartrely reprot", SearchOptions{}},
+		{"regex", `pa(y|ssport)`, SearchOptions{Regex: true}},
+	}
+
+	for _, q := range queries {
+		b.Run(q.name, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := tm.Search(q.query, q.opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
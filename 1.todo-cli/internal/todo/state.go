@@ -0,0 +1,140 @@
+package todo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/neel07sanghvi/todo-cli/internal/dates"
+)
+
+// States a todo can be in, as reported by State
+const (
+	StatePending   = "pending"
+	StateCompleted = "completed"
+	StateSnoozed   = "snoozed"
+	StateWaiting   = "waiting"
+)
+
+// DefaultFollowUpDays is when a waiting todo comes back if no follow-up
+// date is given
+const DefaultFollowUpDays = 7
+
+// State returns whether the todo is completed, snoozed, waiting or pending
+// at now. A snoozed todo is pending again once its day has come; a waiting
+// todo stays waiting until it is resumed or completed.
+func (t *Todo) State(now time.Time) string {
+	switch {
+	case t.Completed:
+		return StateCompleted
+	case t.Waiting:
+		return StateWaiting
+	case t.SnoozedUntil != nil && now.Before(*t.SnoozedUntil):
+		return StateSnoozed
+	default:
+		return StatePending
+	}
+}
+
+// Hidden reports whether listings leave the todo out by default at now:
+// while it is snoozed, and while it is waiting until its follow-up date. A
+// waiting todo without one is never hidden, so it can't be lost.
+func (t *Todo) Hidden(now time.Time) bool {
+	switch t.State(now) {
+	case StateSnoozed:
+		return true
+	case StateWaiting:
+		return t.FollowUp != nil && now.Before(*t.FollowUp)
+	default:
+		return false
+	}
+}
+
+// Snooze hides the todo until the start of day until
+func (t *Todo) Snooze(until time.Time) {
+	t.SnoozedUntil = &until
+}
+
+// Wait marks the todo as waiting for someone else, to be followed up on at
+// followUp if it isn't nil
+func (t *Todo) Wait(waitingFor string, followUp *time.Time) {
+	t.Waiting = true
+	t.WaitingFor = waitingFor
+	t.FollowUp = followUp
+}
+
+// Resume ends any snooze or wait, making the todo pending again
+func (t *Todo) Resume() {
+	t.SnoozedUntil = nil
+	t.Waiting = false
+	t.WaitingFor = ""
+	t.FollowUp = nil
+}
+
+// stateLabel describes a snoozed or waiting todo for Format
+func (t *Todo) stateLabel(now time.Time, d Display) string {
+	switch t.State(now) {
+	case StateSnoozed:
+		return d.Colorize(ColorDim, fmt.Sprintf(" [snoozed until %s]", t.SnoozedUntil.Format(dates.Layout)))
+
+	case StateWaiting:
+		label := "waiting"
+
+		if t.WaitingFor != "" {
+			label += " for " + t.WaitingFor
+		}
+
+		if t.FollowUp == nil {
+			return d.Colorize(ColorDim, " ["+label+"]")
+		}
+
+		if now.Before(*t.FollowUp) {
+			return d.Colorize(ColorDim, fmt.Sprintf(" [%s, follow up %s]", label, t.FollowUp.Format(dates.Layout)))
+		}
+
+		return d.Colorize(ColorYellow, fmt.Sprintf(" [%s, follow up since %s]", label, t.FollowUp.Format(dates.Layout)))
+	}
+
+	return ""
+}
+
+// SnoozeTodo hides a pending todo from listings until the start of day until
+func (tm *TodoManager) SnoozeTodo(id int, until time.Time) error {
+	if err := tm.checkPending(id); err != nil {
+		return err
+	}
+
+	return tm.modify(id, EventModify, func(todo *Todo) {
+		todo.Snooze(until)
+	})
+}
+
+// WaitTodo marks a pending todo as waiting for someone else, optionally
+// until a follow-up date when it shows up in listings again
+func (tm *TodoManager) WaitTodo(id int, waitingFor string, followUp *time.Time) error {
+	if err := tm.checkPending(id); err != nil {
+		return err
+	}
+
+	return tm.modify(id, EventModify, func(todo *Todo) {
+		todo.Wait(waitingFor, followUp)
+	})
+}
+
+// ResumeTodo makes a snoozed or waiting todo pending again
+func (tm *TodoManager) ResumeTodo(id int) error {
+	return tm.modify(id, EventModify, (*Todo).Resume)
+}
+
+func (tm *TodoManager) checkPending(id int) error {
+	todo, exists := tm.todos[id]
+
+	if !exists {
+		return notFound(id)
+	}
+
+	if todo.Completed {
+		return fmt.Errorf("todo with ID %d is already completed", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHidden(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	yesterday := now.AddDate(0, 0, -1)
+	tomorrow := now.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name      string
+		todo      Todo
+		wantState string
+		want      bool
+	}{
+		{name: "pending", todo: Todo{}, wantState: StatePending, want: false},
+		{name: "completed", todo: Todo{Completed: true}, wantState: StateCompleted, want: false},
+		{name: "snoozed", todo: Todo{SnoozedUntil: &tomorrow}, wantState: StateSnoozed, want: true},
+		{name: "snooze over", todo: Todo{SnoozedUntil: &yesterday}, wantState: StatePending, want: false},
+		{name: "waiting before follow-up", todo: Todo{Waiting: true, FollowUp: &tomorrow}, wantState: StateWaiting, want: true},
+		{name: "waiting after follow-up", todo: Todo{Waiting: true, FollowUp: &yesterday}, wantState: StateWaiting, want: false},
+		{name: "waiting without follow-up", todo: Todo{Waiting: true}, wantState: StateWaiting, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.todo.State(now); got != tt.wantState {
+				t.Errorf("State = %q, want %q", got, tt.wantState)
+			}
+
+			if got := tt.todo.Hidden(now); got != tt.want {
+				t.Errorf("Hidden = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
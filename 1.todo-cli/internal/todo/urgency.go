@@ -0,0 +1,189 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/neel07sanghvi/todo-cli/internal/dates"
+)
+
+// UrgencyWeights sets how much each property of a todo adds to its urgency
+// score. Each property yields a factor between 0 and 1 which is multiplied
+// by its weight; the score is the sum of these products.
+type UrgencyWeights struct {
+	Due       float64 // due date proximity, 1.0 from a week overdue
+	PriorityH float64
+	PriorityM float64
+	PriorityL float64
+	Age       float64 // time since creation, 1.0 from a year old
+	Tags      float64 // 0.8 for one tag, 0.9 for two, 1.0 for three or more
+	Blocked   float64 // waiting on a pending dependency; usually negative
+}
+
+// DefaultUrgencyWeights returns the weights used unless the config overrides them
+func DefaultUrgencyWeights() UrgencyWeights {
+	return UrgencyWeights{
+		Due:       12,
+		PriorityH: 6,
+		PriorityM: 3.9,
+		PriorityL: 1.8,
+		Age:       2,
+		Tags:      1,
+		Blocked:   -5,
+	}
+}
+
+// UrgencyTerm is one property's contribution to an urgency score
+type UrgencyTerm struct {
+	Name   string
+	Detail string
+	Factor float64
+	Weight float64
+}
+
+// Value is the amount the term adds to the score
+func (u UrgencyTerm) Value() float64 {
+	return u.Factor * u.Weight
+}
+
+// Urgency is a score together with the terms it was built from
+type Urgency struct {
+	Score float64
+	Terms []UrgencyTerm
+}
+
+// Urgency computes how pressing a todo is with the manager's weights.
+// Completed todos score 0.
+func (tm *TodoManager) Urgency(t *Todo, now time.Time) Urgency {
+	return tm.weights.urgency(t, tm.IsBlocked(t), now)
+}
+
+// urgency scores t with the weights w; blocked says whether t waits on a
+// pending dependency
+func (w UrgencyWeights) urgency(t *Todo, blocked bool, now time.Time) Urgency {
+	var u Urgency
+
+	if t.Completed {
+		return u
+	}
+
+	add := func(name, detail string, factor, weight float64) {
+		if factor == 0 || weight == 0 {
+			return
+		}
+
+		u.Terms = append(u.Terms, UrgencyTerm{Name: name, Detail: detail, Factor: factor, Weight: weight})
+		u.Score += factor * weight
+	}
+
+	if t.Due != nil {
+		add("due", describeDue(dates.DaysBetween(now, *t.Due)), dueFactor(dates.DaysBetween(now, *t.Due)), w.Due)
+	}
+
+	switch t.Priority {
+	case "H":
+		add("priority", "H", 1, w.PriorityH)
+	case "M":
+		add("priority", "M", 1, w.PriorityM)
+	case "L":
+		add("priority", "L", 1, w.PriorityL)
+	}
+
+	ageDays := now.Sub(t.CreatedAt).Hours() / 24
+	add("age", fmt.Sprintf("%.0f days old", ageDays), min(max(ageDays/365, 0), 1), w.Age)
+
+	switch n := len(t.Tags); {
+	case n == 1:
+		add("tags", "1 tag", 0.8, w.Tags)
+	case n == 2:
+		add("tags", "2 tags", 0.9, w.Tags)
+	case n > 2:
+		add("tags", fmt.Sprintf("%d tags", n), 1, w.Tags)
+	}
+
+	if blocked {
+		add("blocked", "waiting on a pending dependency", 1, w.Blocked)
+	}
+
+	return u
+}
+
+// dueFactor grows linearly from 0.2 two weeks before the due date to 1.0
+// a week after it
+func dueFactor(daysUntil int) float64 {
+	overdue := float64(-daysUntil)
+
+	switch {
+	case overdue >= 7:
+		return 1
+	case overdue >= -14:
+		return (overdue+14)*0.8/21 + 0.2
+	default:
+		return 0.2
+	}
+}
+
+func describeDue(daysUntil int) string {
+	switch {
+	case daysUntil == 0:
+		return "due today"
+	case daysUntil == 1:
+		return "due tomorrow"
+	case daysUntil > 1:
+		return fmt.Sprintf("due in %d days", daysUntil)
+	case daysUntil == -1:
+		return "1 day overdue"
+	default:
+		return fmt.Sprintf("%d days overdue", -daysUntil)
+	}
+}
+
+// IsBlocked reports whether t depends on a todo in this list that is still pending
+func (tm *TodoManager) IsBlocked(t *Todo) bool {
+	for _, uuid := range t.Depends {
+		for _, other := range tm.todos {
+			if other.UUID == uuid && !other.Completed {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Next returns the most urgent pending todo that isn't blocked, snoozed or waiting
+func (tm *TodoManager) Next(now time.Time) (*Todo, bool) {
+	var candidates []*Todo
+
+	for _, t := range tm.GetPendingTodos() {
+		if !tm.IsBlocked(t) && !t.Hidden(now) {
+			candidates = append(candidates, t)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, false
+	}
+
+	tm.sortByUrgency(candidates, now)
+
+	return candidates[0], true
+}
+
+// sortByUrgency puts the most urgent todos first, breaking ties by ID
+func (tm *TodoManager) sortByUrgency(todos []*Todo, now time.Time) {
+	scores := make(map[*Todo]float64, len(todos))
+
+	for _, t := range todos {
+		scores[t] = tm.Urgency(t, now).Score
+	}
+
+	sort.SliceStable(todos, func(i, j int) bool {
+		if scores[todos[i]] != scores[todos[j]] {
+			return scores[todos[i]] > scores[todos[j]]
+		}
+
+		return todos[i].ID < todos[j].ID
+	})
+}
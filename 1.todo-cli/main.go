@@ -2,20 +2,61 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
 
+	"github.com/neel07sanghvi/todo-cli/internal/config"
+	"github.com/neel07sanghvi/todo-cli/internal/hooks"
+	"github.com/neel07sanghvi/todo-cli/internal/store"
 	"github.com/neel07sanghvi/todo-cli/internal/todo"
 )
 
 func main() {
-	todoManager := todo.NewTodoManager()
+	cfg := loadConfig()
+
+	dataDir := cfg.DataDir
+
+	if dataDir == "" {
+		dir, err := store.DefaultDir()
+
+		if err != nil {
+			fmt.Printf("Cannot determine data directory: %v\n", err)
+			os.Exit(1)
+		}
+
+		dataDir = dir
+	}
+
+	todoStore, err := store.Open(dataDir)
+
+	if err != nil {
+		fmt.Printf("Cannot open todo lists: %v\n", err)
+		os.Exit(1)
+	}
+
 	scanner := bufio.NewScanner(os.Stdin)
+	todoStore.SetPassphraseFunc(listPassphrase(scanner))
+	todoStore.SetHooks(newHookRunner(cfg).ForList)
+	todoStore.SetUrgencyWeights(cfg.Urgency)
+
+	todoManager, err := todoStore.List(todoStore.Current())
+
+	if err != nil {
+		fmt.Printf("Cannot start: %v\n", err)
+		os.Exit(1)
+	}
+
+	a := &app{cfg: cfg, display: cfg.Display(), store: todoStore, todos: todoManager, scanner: scanner, commands: newCommands()}
 
 	fmt.Println("=== Welcome to Todo CLI ===")
-	fmt.Println("Commands: add, list, update, delete, complete, incomplete, help, exit")
+	fmt.Printf("Commands: %s\n", commandNames(a.commands))
+	fmt.Printf("Using list: %s\n", todoStore.Current())
+
+	if path := todoStore.Override(); path != "" {
+		fmt.Printf("(selected by %s)\n", path)
+	}
 
 	for {
 		fmt.Print("\n> ")
@@ -30,118 +71,94 @@ func main() {
 			continue
 		}
 
-		parts := strings.SplitN(input, " ", 2)
-		command := strings.ToLower(parts[0])
-
-		switch command {
-		case "add":
-			if len(parts) < 2 {
-				fmt.Println("Usage: add <task description>")
-				continue
-			}
-
-			task := parts[1]
-			id := todoManager.AddTodo(task)
-
-			fmt.Printf("Todo added with ID: %d\n", id)
-
-		case "list":
-			todoManager.ListTodos()
-
-		case "update":
-			if len(parts) < 2 {
-				fmt.Println("Usage: update <id> <new description>")
-				continue
-			}
-
-			updateParts := strings.SplitN(parts[1], " ", 2)
-
-			if len(updateParts) < 2 {
-				fmt.Println("Usage: update <id> <new description>")
-				continue
-			}
-
-			id, err := strconv.Atoi(updateParts[0])
-
-			if err != nil {
-				fmt.Println("Invalid ID. Please provide a valid number.")
-				continue
-			}
-
-			newTask := updateParts[1]
-
-			if todoManager.UpdateTodo(id, newTask) {
-				fmt.Printf("Todo with ID %d updated successfully\n", id)
-			} else {
-				fmt.Printf("Todo with ID %d not found\n", id)
-			}
-
-		case "delete":
-			if len(parts) < 2 {
-				fmt.Println("Usage: delete <id>")
-				continue
-			}
-
-			id, err := strconv.Atoi(parts[1])
-
-			if err != nil {
-				fmt.Println("Invalid ID. Please provide a valid number.")
-				continue
-			}
-
-			if todoManager.DeleteTodo(id) {
-				fmt.Printf("Todo with ID %d deleted successfully\n", id)
-			} else {
-				fmt.Printf("Todo with ID %d not found\n", id)
-			}
-
-		case "complete":
-			if len(parts) < 2 {
-				fmt.Println("Usage: complete <id>")
-				continue
-			}
-
-			id, err := strconv.Atoi(parts[1])
-
-			if err != nil {
-				fmt.Println("Invalid ID. Please provide a valid number.")
-				continue
-			}
-
-			if todoManager.CompleteTodo(id) {
-				fmt.Printf("Todo with ID %d marked as completed\n", id)
-			} else {
-				fmt.Printf("Todo with ID %d not found\n", id)
-			}
-
-		case "incomplete":
-			if len(parts) < 2 {
-				fmt.Println("Usage: incomplete <id>")
-				continue
-			}
-
-			id, err := strconv.Atoi(parts[1])
-
-			if err != nil {
-				fmt.Println("Invalid ID. Please provide a valid number.")
-				continue
-			}
-
-			if todoManager.IncompleteTodo(id) {
-				fmt.Printf("Todo with ID %d marked as incomplete\n", id)
-			} else {
-				fmt.Printf("Todo with ID %d not found\n", id)
-			}
-
-		case "help":
-			printHelp()
-
-		case "exit", "quit":
+		if err := a.execute(cfg.ExpandAlias(input)); errors.Is(err, errQuit) {
 			fmt.Println("Goodbye!")
 			return
+		}
+	}
+}
+
+// app is the state the REPL commands work on
+type app struct {
+	cfg      *config.Config
+	display  todo.Display
+	store    *store.Store
+	todos    *todo.TodoManager // the current list
+	scanner  *bufio.Scanner
+	commands []*command
+}
+
+// execute runs one line of input, saving the lists if the command may have
+// changed them. Only errQuit is returned; other errors are printed.
+func (a *app) execute(input string) error {
+	words, err := tokenize(input)
+
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return nil
+	}
+
+	name := strings.ToLower(words[0].text)
+	c, ok := findCommand(a.commands, name)
 
-		default:
-			fmt.Printf("Unknown command: %s. Type 'help' for available commands.\n", command)
+	if !ok {
+		fmt.Printf("Unknown command: %s. Type 'help' for available commands.\n", name)
+		return nil
+	}
+
+	inv, err := c.parse(words[1:])
+
+	if err != nil {
+		fmt.Printf("Error: %v\nUsage: %s\n", err, c.usage())
+		return nil
+	}
+
+	if err := c.run(a, inv); err != nil {
+		if errors.Is(err, errQuit) {
+			return err
 		}
+
+		fmt.Printf("Error: %v\n", err)
+		return nil
+	}
+
+	if c.readOnly {
+		return nil
+	}
+
+	if err := a.store.Save(); err != nil {
+		fmt.Printf("Failed to save todos: %v\n", err)
+	}
+
+	return nil
+}
+
+// newHookRunner sets up the lifecycle hooks from the configured directory
+func newHookRunner(cfg *config.Config) *hooks.Runner {
+	dir := cfg.HooksDir
+
+	if dir == "" {
+		// Without a config directory there is nowhere to look for hooks
+		dir, _ = hooks.DefaultDir()
+	}
+
+	return hooks.New(dir, cfg.HookTimeout)
+}
+
+// loadConfig reads the user's config file, exiting on invalid settings
+func loadConfig() *config.Config {
+	path, err := config.Path()
+
+	if err != nil {
+		return config.Default()
+	}
+
+	cfg, err := config.Load(path)
+
+	if err != nil {
+		fmt.Printf("Invalid config: %v\n", err)
+		os.Exit(1)
 	}
+
+	return cfg
 }
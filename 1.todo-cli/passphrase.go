@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// promptPassphrase asks for a passphrase on the terminal without echoing it.
+// When stdin isn't a terminal the input is read as is.
+func promptPassphrase(scanner *bufio.Scanner, prompt string) (string, error) {
+	fmt.Print(prompt)
+
+	if setEcho(false) == nil {
+		defer func() {
+			setEcho(true)
+			fmt.Println()
+		}()
+	}
+
+	if !scanner.Scan() {
+		return "", errors.New("no passphrase given")
+	}
+
+	passphrase := strings.TrimRight(scanner.Text(), "\r\n")
+
+	if passphrase == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+
+	return passphrase, nil
+}
+
+// promptNewPassphrase asks for a new passphrase twice and checks they match
+func promptNewPassphrase(scanner *bufio.Scanner) (string, error) {
+	passphrase, err := promptPassphrase(scanner, "New passphrase: ")
+
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := promptPassphrase(scanner, "Repeat new passphrase: ")
+
+	if err != nil {
+		return "", err
+	}
+
+	if passphrase != confirm {
+		return "", errors.New("passphrases do not match")
+	}
+
+	return passphrase, nil
+}
+
+// listPassphrase returns the passphrase used to open an encrypted list:
+// TODO_PASSPHRASE if set, otherwise whatever the user types
+func listPassphrase(scanner *bufio.Scanner) func(list string) (string, error) {
+	return func(list string) (string, error) {
+		if passphrase := os.Getenv("TODO_PASSPHRASE"); passphrase != "" {
+			return passphrase, nil
+		}
+
+		return promptPassphrase(scanner, fmt.Sprintf("Passphrase for list %s: ", list))
+	}
+}
+
+// setEcho turns terminal echo on or off using stty, which fails harmlessly
+// when stdin isn't a terminal
+func setEcho(on bool) error {
+	arg := "-echo"
+
+	if on {
+		arg = "echo"
+	}
+
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+
+	return cmd.Run()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// token is one word of a command line
+type token struct {
+	text   string
+	quoted bool // part of the word was quoted or escaped, so it is taken literally
+}
+
+// tokenize splits a command line into words the way a POSIX shell would:
+// whitespace separates words, 'single quotes' keep everything literally,
+// "double quotes" keep everything except \" and \\, and a backslash
+// outside quotes escapes the next character.
+func tokenize(line string) ([]token, error) {
+	var tokens []token
+	var current strings.Builder
+	inWord, quoted := false, false
+
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, token{text: current.String(), quoted: quoted})
+		}
+
+		current.Reset()
+		inWord, quoted = false, false
+	}
+
+	runes := []rune(line)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == ' ' || r == '\t':
+			flush()
+
+		case r == '\\':
+			if i+1 == len(runes) {
+				return nil, errors.New("unfinished escape at end of line")
+			}
+
+			i++
+			current.WriteRune(runes[i])
+			inWord, quoted = true, true
+
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+
+			if end < 0 {
+				return nil, errors.New("unterminated ' quote")
+			}
+
+			current.WriteString(string(runes[i+1 : end]))
+			i = end
+			inWord, quoted = true, true
+
+		case r == '"':
+			i++
+
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+					i++
+				}
+
+				current.WriteRune(runes[i])
+			}
+
+			if i == len(runes) {
+				return nil, errors.New(`unterminated " quote`)
+			}
+
+			inWord, quoted = true, true
+
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	flush()
+
+	return tokens, nil
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+const apiKeyPrefix = "uk_"
+
+// NewAPIKey makes a new API key of the form uk_<id>_<secret>. The ID is
+// stored in the clear to find the key again; of the secret only the hash
+// returned by HashAPIKeySecret should be stored.
+func NewAPIKey() (key, id, secret string) {
+	id = strings.ToLower(rand.Text()[:12])
+	secret = rand.Text()
+
+	return apiKeyPrefix + id + "_" + secret, id, secret
+}
+
+// ParseAPIKey splits a key made by NewAPIKey into its ID and secret
+func ParseAPIKey(key string) (id, secret string, err error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), "_")
+
+	if !ok || !strings.HasPrefix(key, apiKeyPrefix) || id == "" || secret == "" {
+		return "", "", ErrInvalidToken
+	}
+
+	return id, secret, nil
+}
+
+// HashAPIKeySecret hashes the secret part of an API key. Unlike passwords,
+// secrets are long and random, so a single SHA-256 is enough: there is no
+// dictionary to try.
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckAPIKeySecret reports whether secret matches a hash made by HashAPIKeySecret
+func CheckAPIKeySecret(hash, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(HashAPIKeySecret(secret))) == 1
+}
@@ -0,0 +1,92 @@
+// Package auth has the building blocks of the API's authentication:
+// password hashing, signed access tokens and refresh tokens.
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MinPasswordLength is the length a new password must have at least
+const MinPasswordLength = 8
+
+const (
+	hashScheme     = "pbkdf2-sha256"
+	hashIterations = 600_000 // OWASP's recommendation for PBKDF2-HMAC-SHA256
+	saltLength     = 16
+	keyLength      = 32
+)
+
+// HashPassword derives a hash of password with PBKDF2-HMAC-SHA256 and a
+// random salt. The result records the scheme, iterations and salt, e.g.
+// pbkdf2-sha256$600000$<salt>$<key>, so that they can change later
+// without breaking the hashes already stored.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	rand.Read(salt)
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, hashIterations, keyLength)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		hashScheme,
+		strconv.Itoa(hashIterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$"), nil
+}
+
+// CheckPassword reports whether password matches a hash made by
+// HashPassword. An empty or malformed hash matches no password.
+func CheckPassword(hash, password string) bool {
+	iterations, salt, want, err := parseHash(hash)
+
+	if err != nil {
+		return false
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+
+	return err == nil && subtle.ConstantTimeCompare(key, want) == 1
+}
+
+// dummyHash is checked against when a login names no known user, so that
+// the response takes as long as for a wrong password
+var dummyHash, _ = HashPassword("not a real password")
+
+// CheckNoPassword spends as long as CheckPassword does and returns false
+func CheckNoPassword(password string) bool {
+	CheckPassword(dummyHash, password)
+	return false
+}
+
+func parseHash(hash string) (iterations int, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return 0, nil, nil, fmt.Errorf("unknown password hash scheme")
+	}
+
+	if iterations, err = strconv.Atoi(parts[1]); err != nil || iterations < 1 {
+		return 0, nil, nil, fmt.Errorf("invalid iteration count %q", parts[1])
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+		return 0, nil, nil, fmt.Errorf("invalid salt: %w", err)
+	}
+
+	if key, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil || len(key) == 0 {
+		return 0, nil, nil, fmt.Errorf("invalid key")
+	}
+
+	return iterations, salt, key, nil
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// RefreshTokens hands out refresh tokens and keeps track of them in
+// memory, so they are all invalid after a restart. Only a hash of each
+// token is kept. It is safe for concurrent use.
+//
+// Refresh tokens are single use: Rotate replaces a token with a new one.
+// Presenting a token that was already rotated means it was copied, so all
+// of that user's tokens are revoked.
+type RefreshTokens struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	tokens map[string]*refreshToken // by hash
+}
+
+type refreshToken struct {
+	userID    int
+	expiresAt time.Time
+	used      bool // rotated; kept until it expires to detect reuse
+}
+
+func NewRefreshTokens(ttl time.Duration) *RefreshTokens {
+	return &RefreshTokens{ttl: ttl, tokens: make(map[string]*refreshToken)}
+}
+
+// Issue returns a new refresh token for a user
+func (rt *RefreshTokens) Issue(userID int) string {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	return rt.issue(userID, time.Now())
+}
+
+// Rotate uses up a refresh token and returns the user it belongs to with a
+// new token for them. It fails with ErrInvalidToken for an unknown, expired
+// or already used token.
+func (rt *RefreshTokens) Rotate(token string) (int, string, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	now := time.Now()
+	t, ok := rt.tokens[hashToken(token)]
+
+	switch {
+	case !ok || now.After(t.expiresAt):
+		return 0, "", ErrInvalidToken
+	case t.used:
+		rt.revokeUser(t.userID)
+		return 0, "", ErrInvalidToken
+	}
+
+	t.used = true
+
+	return t.userID, rt.issue(t.userID, now), nil
+}
+
+// Revoke invalidates a refresh token; unknown tokens are ignored
+func (rt *RefreshTokens) Revoke(token string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	delete(rt.tokens, hashToken(token))
+}
+
+// RevokeUser invalidates every refresh token of a user, e.g. when their
+// password changes
+func (rt *RefreshTokens) RevokeUser(userID int) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.revokeUser(userID)
+}
+
+func (rt *RefreshTokens) issue(userID int, now time.Time) string {
+	// Dropping the expired tokens here keeps the map from growing forever
+	for hash, t := range rt.tokens {
+		if now.After(t.expiresAt) {
+			delete(rt.tokens, hash)
+		}
+	}
+
+	token := rand.Text()
+	rt.tokens[hashToken(token)] = &refreshToken{userID: userID, expiresAt: now.Add(rt.ttl)}
+
+	return token
+}
+
+func (rt *RefreshTokens) revokeUser(userID int) {
+	for hash, t := range rt.tokens {
+		if t.userID == userID {
+			delete(rt.tokens, hash)
+		}
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/neel07sanghvi/crud-api/auth"
+	"github.com/neel07sanghvi/crud-api/models"
+	"github.com/neel07sanghvi/crud-api/storage"
+)
+
+const (
+	defaultKeyLifetime = 90 * 24 * time.Hour
+	maxKeyLifetime     = 365 * 24 * time.Hour
+)
+
+type APIKeyHandler struct {
+	keys storage.APIKeyRepository
+}
+
+func NewAPIKeyHandler(keys storage.APIKeyRepository) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+func (h *APIKeyHandler) Register(rt *Router) {
+	rt.HandleFunc(http.MethodGet, "/api-keys", h.handleList)
+	rt.HandleFunc(http.MethodPost, "/api-keys", h.handleCreate)
+	rt.HandleFunc(http.MethodDelete, "/api-keys/{id}", h.handleRevoke)
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"` // defaults to 90 days from now
+}
+
+// createdAPIKey is the response to creating a key, the only one that
+// includes the key itself
+type createdAPIKey struct {
+	*models.APIKey
+	Key string `json:"key"`
+}
+
+func (h *APIKeyHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keys.ListAPIKeys(r.Context())
+
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req createAPIKeyRequest
+
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(defaultKeyLifetime)
+
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+
+	var invalid []InvalidParam
+
+	if req.Name = strings.TrimSpace(req.Name); req.Name == "" {
+		invalid = append(invalid, InvalidParam{Name: "name", Reason: "is required"})
+	}
+
+	if len(req.Scopes) == 0 {
+		invalid = append(invalid, InvalidParam{Name: "scopes", Reason: "needs at least one of " + strings.Join(models.Scopes, ", ")})
+	}
+
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.Scopes, scope) {
+			invalid = append(invalid, InvalidParam{Name: "scopes", Reason: "has unknown scope " + scope + "; the scopes are " + strings.Join(models.Scopes, ", ")})
+		}
+	}
+
+	if !expiresAt.After(now) || expiresAt.After(now.Add(maxKeyLifetime)) {
+		invalid = append(invalid, InvalidParam{Name: "expires_at", Reason: "must be in the future and at most a year away"})
+	}
+
+	if len(invalid) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "the API key is invalid", invalid...)
+		return
+	}
+
+	creator, _ := UserFromContext(r.Context())
+	key, id, secret := auth.NewAPIKey()
+
+	apiKey := &models.APIKey{
+		ID:         id,
+		Name:       req.Name,
+		Scopes:     slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		CreatedBy:  creator.ID,
+		CreatedAt:  now,
+		ExpiresAt:  expiresAt,
+		SecretHash: auth.HashAPIKeySecret(secret),
+	}
+
+	if err := h.keys.CreateAPIKey(r.Context(), apiKey); err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, createdAPIKey{APIKey: apiKey, Key: key})
+}
+
+// handleRevoke revokes a key at once. It stays in the list, marked revoked.
+func (h *APIKeyHandler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := h.keys.RevokeAPIKey(r.Context(), r.PathValue("id"), time.Now()); err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/neel07sanghvi/crud-api/auth"
+	"github.com/neel07sanghvi/crud-api/models"
+	"github.com/neel07sanghvi/crud-api/storage"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 7 * 24 * time.Hour
+	// keyTouchInterval is how often an API key's last use is written, so
+	// that a busy key doesn't write to the storage on every request
+	keyTouchInterval = time.Minute
+)
+
+// Authenticator logs users in and checks the access tokens it issued and
+// the API keys. Access tokens are short-lived JWTs; a refresh token, which
+// is single use, gets a new pair without the password.
+type Authenticator struct {
+	storage storage.UserRepository
+	keys    storage.APIKeyRepository
+	key     []byte
+	refresh *auth.RefreshTokens
+}
+
+// NewAuthenticator signs access tokens with key, which should be at least
+// 32 random bytes
+func NewAuthenticator(storage storage.UserRepository, keys storage.APIKeyRepository, key []byte) *Authenticator {
+	return &Authenticator{
+		storage: storage,
+		keys:    keys,
+		key:     key,
+		refresh: auth.NewRefreshTokens(refreshTokenTTL),
+	}
+}
+
+type (
+	userContextKey   struct{}
+	apiKeyContextKey struct{}
+)
+
+// UserFromContext returns the user authenticated by Require with an access token
+func UserFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(userContextKey{}).(*models.User)
+	return user, ok
+}
+
+// APIKeyFromContext returns the API key authenticated by Require
+func APIKeyFromContext(ctx context.Context) (*models.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(*models.APIKey)
+	return key, ok
+}
+
+// Register adds the login routes to public and the password route to
+// protected, which must run Require and Authorize
+func (a *Authenticator) Register(public, protected *Router) {
+	public.HandleFunc(http.MethodPost, "/auth/login", a.handleLogin)
+	public.HandleFunc(http.MethodPost, "/auth/refresh", a.handleRefresh)
+	public.HandleFunc(http.MethodPost, "/auth/logout", a.handleLogout)
+	protected.HandleFunc(http.MethodPut, "/users/{id}/password", a.handleSetPassword)
+}
+
+// Require is middleware that only lets requests with a valid access token
+// (Authorization: Bearer <token>) or API key (Authorization: ApiKey <key>)
+// through, answering 401 otherwise. The token's user is looked up again, so
+// deleting a user locks them out at once.
+func (a *Authenticator) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+
+		if strings.EqualFold(scheme, "ApiKey") && token != "" {
+			a.requireAPIKey(w, r, token, next)
+			return
+		}
+
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			writeUnauthorized(w, r, "an access token or API key is required: Authorization: Bearer <token> or Authorization: ApiKey <key>")
+			return
+		}
+
+		claims, err := auth.VerifyToken(a.key, token, time.Now())
+
+		if err != nil {
+			writeUnauthorized(w, r, err.Error())
+			return
+		}
+
+		id, _ := strconv.Atoi(claims.Subject)
+		user, err := a.storage.GetUserByID(r.Context(), id)
+
+		if errors.Is(err, storage.ErrNotFound) {
+			writeUnauthorized(w, r, "the token's user no longer exists")
+			return
+		}
+
+		if err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey{}, user)))
+	})
+}
+
+func (a *Authenticator) requireAPIKey(w http.ResponseWriter, r *http.Request, token string, next http.Handler) {
+	id, secret, err := auth.ParseAPIKey(token)
+
+	if err != nil {
+		writeUnauthorized(w, r, "malformed API key")
+		return
+	}
+
+	key, err := a.keys.GetAPIKey(r.Context(), id)
+
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		auth.CheckAPIKeySecret("", secret)
+		writeUnauthorized(w, r, "invalid API key")
+		return
+	}
+
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+
+	if !auth.CheckAPIKeySecret(key.SecretHash, secret) {
+		writeUnauthorized(w, r, "invalid API key")
+		return
+	}
+
+	if !key.Active(now) {
+		writeUnauthorized(w, r, "the API key is revoked or expired")
+		return
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= keyTouchInterval {
+		// Failing to record the use shouldn't fail the request
+		if err := a.keys.TouchAPIKey(r.Context(), key.ID, now); err != nil {
+			slog.WarnContext(r.Context(), "recording API key use failed", "request_id", RequestIDFromContext(r.Context()), "key", key.ID, "error", err)
+		}
+	}
+
+	next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key)))
+}
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// tokenResponse follows the shape of an OAuth 2.0 token response (RFC 6749)
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // seconds
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (a *Authenticator) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	email, err := models.NormalizeEmail(req.Email)
+
+	if err != nil {
+		auth.CheckNoPassword(req.Password)
+		writeUnauthorized(w, r, "wrong email or password")
+		return
+	}
+
+	user, err := a.storage.GetUserByEmail(r.Context(), email)
+
+	if errors.Is(err, storage.ErrNotFound) {
+		auth.CheckNoPassword(req.Password)
+		writeUnauthorized(w, r, "wrong email or password")
+		return
+	}
+
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+
+	if !auth.CheckPassword(user.PasswordHash, req.Password) {
+		writeUnauthorized(w, r, "wrong email or password")
+		return
+	}
+
+	a.writeTokens(w, user.ID, a.refresh.Issue(user.ID))
+}
+
+// handleRefresh swaps a refresh token for a new access and refresh token
+func (a *Authenticator) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	id, refreshToken, err := a.refresh.Rotate(req.RefreshToken)
+
+	if err != nil {
+		writeUnauthorized(w, r, "the refresh token is invalid, expired or already used")
+		return
+	}
+
+	if _, err := a.storage.GetUserByID(r.Context(), id); err != nil {
+		a.refresh.Revoke(refreshToken)
+
+		if errors.Is(err, storage.ErrNotFound) {
+			writeUnauthorized(w, r, "the token's user no longer exists")
+		} else {
+			writeStorageError(w, r, err)
+		}
+
+		return
+	}
+
+	a.writeTokens(w, id, refreshToken)
+}
+
+// handleLogout revokes a refresh token. The access token stays valid until
+// it expires, which is why it is short-lived.
+func (a *Authenticator) handleLogout(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	a.refresh.Revoke(req.RefreshToken)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type passwordRequest struct {
+	Password string `json:"password"`
+}
+
+// handleSetPassword changes a user's password and revokes their refresh
+// tokens, logging them out everywhere once their access tokens expire
+func (a *Authenticator) handleSetPassword(w http.ResponseWriter, r *http.Request) {
+	id, ok := userID(w, r)
+
+	if !ok {
+		return
+	}
+
+	var req passwordRequest
+
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	hash, invalid := hashPassword(req.Password)
+
+	if invalid != nil {
+		writeProblem(w, r, http.StatusBadRequest, "the password is invalid", *invalid)
+		return
+	}
+
+	if err := a.storage.SetPassword(r.Context(), id, hash); err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+
+	a.refresh.RevokeUser(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Authenticator) writeTokens(w http.ResponseWriter, userID int, refreshToken string) {
+	now := time.Now()
+	accessToken := auth.SignToken(a.key, auth.Claims{
+		Subject:   strconv.Itoa(userID),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(accessTokenTTL).Unix(),
+	})
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, tokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+	})
+}
+
+// hashPassword checks a new password and hashes it
+func hashPassword(password string) (string, *InvalidParam) {
+	if utf8.RuneCountInString(password) < auth.MinPasswordLength {
+		return "", &InvalidParam{Name: "password", Reason: "must have at least " + strconv.Itoa(auth.MinPasswordLength) + " characters"}
+	}
+
+	hash, err := auth.HashPassword(password)
+
+	if err != nil {
+		return "", &InvalidParam{Name: "password", Reason: err.Error()}
+	}
+
+	return hash, nil
+}
+
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="users"`)
+	writeProblem(w, r, http.StatusUnauthorized, detail)
+}
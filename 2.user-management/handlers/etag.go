@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/neel07sanghvi/crud-api/models"
+	"github.com/neel07sanghvi/crud-api/storage"
+)
+
+// etag returns the strong entity tag of a user. The version changes with
+// every update, so it identifies the representation without hashing it.
+func etag(user *models.User) string {
+	return `"` + strconv.Itoa(user.Version) + `"`
+}
+
+// matchETag reports whether a list of entity tags from an If-Match or
+// If-None-Match header includes tag. Weak tags (W/"...") only match with
+// the weak comparison that If-None-Match uses.
+func matchETag(header, tag string, weak bool) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+
+		if weak {
+			t = strings.TrimPrefix(t, "W/")
+		}
+
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ifMatch returns the version a PUT or DELETE must apply to: the current
+// version when the If-Match header lists the user's ETag, or AnyVersion
+// without the header. The storage checks the version again as it changes
+// the user, so a change made in between still fails. It answers 412 when
+// the header doesn't match. PATCH reads the user anyway and calls
+// checkIfMatch on it directly.
+func (h *UserHandler) ifMatch(w http.ResponseWriter, r *http.Request, id int) (int, bool) {
+	if r.Header.Get("If-Match") == "" {
+		return storage.AnyVersion, true
+	}
+
+	user, err := h.storage.GetUserByID(r.Context(), id)
+
+	if err != nil {
+		writeStorageError(w, r, err)
+		return 0, false
+	}
+
+	if !checkIfMatch(w, r, user) {
+		return 0, false
+	}
+
+	return user.Version, true
+}
+
+// checkIfMatch reports whether a PUT, PATCH or DELETE may change user: it
+// may without an If-Match header or when the header lists the user's ETag,
+// and otherwise checkIfMatch answers 412
+func checkIfMatch(w http.ResponseWriter, r *http.Request, user *models.User) bool {
+	header := r.Header.Get("If-Match")
+
+	if header != "" && !matchETag(header, etag(user), false) {
+		writeProblem(w, r, http.StatusPreconditionFailed, "the user has changed: its ETag is now "+etag(user))
+		return false
+	}
+
+	return true
+}
+
+// writeUser writes a user with its ETag
+func writeUser(w http.ResponseWriter, status int, user *models.User) {
+	w.Header().Set("ETag", etag(user))
+	writeJSON(w, status, user)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"time"
+)
+
+// RequestIDHeader carries a request's ID, both from a client or proxy that
+// already assigned one and back in the response
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+// Chain wraps h in middleware, the first of which runs first
+func Chain(h http.Handler, middleware ...func(http.Handler) http.Handler) http.Handler {
+	for _, mw := range slices.Backward(middleware) {
+		h = mw(h)
+	}
+
+	return h
+}
+
+type requestIDContextKey struct{}
+
+// RequestIDFromContext returns the ID given to the request by RequestID
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// RequestID is middleware that gives every request an ID, keeping the one
+// in an incoming X-Request-ID header if it is reasonable, and sends it back
+// in the response so that clients can quote it
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDContextKey{}, id)))
+	})
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, so that a
+// client can't put anything odd in the logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := range len(id) {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// statusWriter remembers the status and size of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// AccessLog is middleware that logs every request once it is answered,
+// with its status, response size and latency. Server errors are logged at
+// the error level.
+func AccessLog(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+
+			next.ServeHTTP(sw, r)
+
+			status := sw.status
+
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			level := slog.LevelInfo
+
+			if status >= 500 {
+				level = slog.LevelError
+			}
+
+			// ServeMux sets r.Pattern on this request as it routes it
+			logger.LogAttrs(r.Context(), level, "request",
+				slog.String("request_id", RequestIDFromContext(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("pattern", r.Pattern),
+				slog.Int("status", status),
+				slog.Int("bytes", sw.bytes),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("user_agent", r.UserAgent()),
+			)
+		})
+	}
+}
+
+// Recover is middleware that turns a panic in a handler into a 500 problem
+// and logs it with its stack, instead of the server dropping the
+// connection. A panic after the response has started can only be logged.
+func Recover(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sw := &statusWriter{ResponseWriter: w}
+
+			defer func() {
+				err := recover()
+
+				if err == nil {
+					return
+				}
+
+				// The server's own way of aborting a response
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				logger.LogAttrs(r.Context(), slog.LevelError, "panic",
+					slog.String("request_id", RequestIDFromContext(r.Context())),
+					slog.Any("error", err),
+					slog.String("stack", string(debug.Stack())),
+				)
+
+				if sw.status == 0 {
+					writeProblem(sw, r, http.StatusInternalServerError, "internal server error")
+				}
+			}()
+
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
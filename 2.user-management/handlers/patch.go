@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The patch documents are applied to a generic JSON document (maps, slices,
+// strings, json.Number, bools and nil) so that they work on any resource
+
+var (
+	// errInvalidPatch means the patch document itself is malformed
+	errInvalidPatch = errors.New("invalid patch")
+	// errPatchFailed means the patch is well formed but can't be applied to
+	// the resource as it is, e.g. a path doesn't exist or a test fails
+	errPatchFailed = errors.New("patch can't be applied")
+)
+
+// decodeJSON decodes a single JSON value, keeping numbers exact
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+
+	return v, nil
+}
+
+// mergePatch applies an RFC 7396 JSON merge patch: members of an object
+// patch replace the target's, recursively, and null removes them
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+
+	if !ok {
+		t = make(map[string]any)
+	}
+
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+		} else {
+			t[name] = mergePatch(t[name], value)
+		}
+	}
+
+	return t
+}
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch applies an RFC 6902 JSON patch to doc, which it may modify.
+// The operations are applied in order and the first one that fails fails
+// the whole patch, so callers should pass a copy they can throw away.
+func applyJSONPatch(doc any, patch []byte) (any, error) {
+	var ops []patchOperation
+
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidPatch, err)
+	}
+
+	for i, op := range ops {
+		var err error
+
+		if doc, err = op.apply(doc); err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func (op patchOperation) apply(doc any) (any, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: missing path", errInvalidPatch)
+	}
+
+	path, err := parsePointer(*op.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var value, from any
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", errInvalidPatch)
+		}
+
+		if value, err = decodeJSON(op.Value); err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidPatch, err)
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: missing from", errInvalidPatch)
+		}
+
+		fromPath, err := parsePointer(*op.From)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" && len(fromPath) < len(path) && slices.Equal(fromPath, path[:len(fromPath)]) {
+			return nil, fmt.Errorf("%w: can't move %s into itself", errInvalidPatch, *op.From)
+		}
+
+		if from, err = get(doc, fromPath); err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			if doc, err = remove(doc, fromPath); err != nil {
+				return nil, err
+			}
+		} else {
+			from = cloneJSON(from)
+		}
+	case "remove":
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", errInvalidPatch, op.Op)
+	}
+
+	switch op.Op {
+	case "add":
+		return add(doc, path, value)
+	case "move", "copy":
+		return add(doc, path, from)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		if len(path) == 0 {
+			return value, nil
+		}
+
+		if doc, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+
+		return add(doc, path, value)
+	default: // test
+		current, err := get(doc, path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !equalJSON(current, value) {
+			return nil, fmt.Errorf("%w: %s doesn't have the tested value", errPatchFailed, *op.Path)
+		}
+
+		return doc, nil
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON pointer such as /tags/0 into its
+// unescaped reference tokens; "" is the whole document
+func parsePointer(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("%w: JSON pointer %q doesn't start with /", errInvalidPatch, s)
+	}
+
+	tokens := strings.Split(s[1:], "/")
+
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			child, ok := node[token]
+
+			if !ok {
+				return nil, fmt.Errorf("%w: no member %q", errPatchFailed, token)
+			}
+
+			doc = child
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%w: %q is below a value that is neither an object nor an array", errPatchFailed, token)
+		}
+	}
+
+	return doc, nil
+}
+
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			if token == "-" {
+				return append(node, value), nil
+			}
+
+			i, err := arrayIndex(token, len(node))
+
+			if err != nil {
+				return nil, err
+			}
+
+			return slices.Insert(node, i, value), nil
+		default:
+			return nil, fmt.Errorf("%w: can't add %q to a value that is neither an object nor an array", errPatchFailed, token)
+		}
+	})
+}
+
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: can't remove the whole document", errPatchFailed)
+	}
+
+	return update(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("%w: no member %q", errPatchFailed, token)
+			}
+
+			delete(node, token)
+			return node, nil
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return slices.Delete(node, i, i+1), nil
+		default:
+			return nil, fmt.Errorf("%w: no member %q", errPatchFailed, token)
+		}
+	})
+}
+
+// update walks doc down to the parent of the last token of path and
+// replaces it with what change returns, since adding to or removing from
+// an array makes a new slice
+func update(doc any, path []string, change func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return change(doc, path[0])
+	}
+
+	child, err := get(doc, path[:1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if child, err = update(child, path[1:], change); err != nil {
+		return nil, err
+	}
+
+	switch node := doc.(type) {
+	case map[string]any:
+		node[path[0]] = child
+	case []any:
+		i, _ := arrayIndex(path[0], len(node)-1)
+		node[i] = child
+	}
+
+	return doc, nil
+}
+
+// arrayIndex parses an array index token, which must be from 0 to last
+func arrayIndex(token string, last int) (int, error) {
+	i, err := strconv.Atoi(token)
+
+	if err != nil || i < 0 || strconv.Itoa(i) != token {
+		return 0, fmt.Errorf("%w: %q is not an array index", errPatchFailed, token)
+	}
+
+	if i > last {
+		return 0, fmt.Errorf("%w: array index %d is out of range", errPatchFailed, i)
+	}
+
+	return i, nil
+}
+
+// equalJSON compares two JSON values as RFC 6902's test operation does:
+// numbers by value and objects regardless of member order
+func equalJSON(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for name, value := range a {
+			other, ok := b[name]
+
+			if !ok || !equalJSON(value, other) {
+				return false
+			}
+		}
+
+		return true
+	case []any:
+		b, ok := b.([]any)
+
+		return ok && slices.EqualFunc(a, b, equalJSON)
+	case json.Number:
+		b, ok := b.(json.Number)
+
+		if !ok {
+			return false
+		}
+
+		x, errA := a.Float64()
+		y, errB := b.Float64()
+
+		return errA == nil && errB == nil && x == y
+	default:
+		return a == b
+	}
+}
+
+func cloneJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+
+		for name, value := range v {
+			c[name] = cloneJSON(value)
+		}
+
+		return c
+	case []any:
+		c := make([]any, len(v))
+
+		for i, value := range v {
+			c[i] = cloneJSON(value)
+		}
+
+		return c
+	default:
+		return v
+	}
+}
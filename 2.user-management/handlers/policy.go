@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/neel07sanghvi/crud-api/models"
+)
+
+// access is how far a role may use a permission
+type access int
+
+const (
+	denied access = iota
+	self          // only on the caller's own user
+	anyone        // on any user
+)
+
+// policy says which roles have a permission, and why the others are
+// denied. API keys have the permission if they have its scope; without a
+// scope no key has it.
+type policy struct {
+	roles  map[string]access
+	reason string
+	scope  string
+}
+
+// assignRole is the permission to give a user a role: to create a user
+// with a role other than member, or to change a user's role
+const assignRole = "assign role"
+
+// policies is the one place that says who may do what. Every route
+// protected by Authorize needs an entry for its ServeMux pattern; routes
+// without one are denied to everybody.
+var policies = map[string]policy{
+	"GET /users": {
+		roles:  map[string]access{models.RoleAdmin: anyone, models.RoleManager: anyone},
+		reason: "members can't list users, only see their own at GET /users/{id}",
+		scope:  models.ScopeUsersRead,
+	},
+	"POST /users": {
+		roles:  map[string]access{models.RoleAdmin: anyone, models.RoleManager: anyone},
+		reason: "members can't create users",
+		scope:  models.ScopeUsersWrite,
+	},
+	"GET /users/{id}": {
+		roles:  map[string]access{models.RoleAdmin: anyone, models.RoleManager: anyone, models.RoleMember: self},
+		reason: "members can only see their own user",
+		scope:  models.ScopeUsersRead,
+	},
+	"PUT /users/{id}": {
+		roles:  map[string]access{models.RoleAdmin: anyone, models.RoleManager: anyone, models.RoleMember: self},
+		reason: "members can only update their own user",
+		scope:  models.ScopeUsersWrite,
+	},
+	"PATCH /users/{id}": {
+		roles:  map[string]access{models.RoleAdmin: anyone, models.RoleManager: anyone, models.RoleMember: self},
+		reason: "members can only update their own user",
+		scope:  models.ScopeUsersWrite,
+	},
+	"DELETE /users/{id}": {
+		roles:  map[string]access{models.RoleAdmin: anyone},
+		reason: "only admins can delete users",
+		scope:  models.ScopeUsersDelete,
+	},
+	"PUT /users/{id}/password": {
+		roles:  map[string]access{models.RoleAdmin: anyone, models.RoleManager: self, models.RoleMember: self},
+		reason: "only admins can change someone else's password",
+	},
+	assignRole: {
+		roles:  map[string]access{models.RoleAdmin: anyone},
+		reason: "only admins can change roles",
+	},
+	"GET /api-keys": {
+		roles:  map[string]access{models.RoleAdmin: anyone},
+		reason: "only admins can manage API keys",
+	},
+	"POST /api-keys": {
+		roles:  map[string]access{models.RoleAdmin: anyone},
+		reason: "only admins can manage API keys",
+	},
+	"DELETE /api-keys/{id}": {
+		roles:  map[string]access{models.RoleAdmin: anyone},
+		reason: "only admins can manage API keys",
+	},
+}
+
+// allowed reports whether the user or API key authenticated in ctx has a
+// permission on the user with ID target (0 when the permission isn't about
+// one user), and if not, why
+func allowed(ctx context.Context, permission string, target int) (bool, string) {
+	p, ok := policies[permission]
+
+	if !ok {
+		return false, "no policy allows " + permission
+	}
+
+	if key, ok := APIKeyFromContext(ctx); ok {
+		switch {
+		case p.scope == "":
+			return false, "API keys can't " + permission
+		case !key.HasScope(p.scope):
+			return false, "the API key needs the scope " + p.scope
+		}
+
+		return true, ""
+	}
+
+	user, ok := UserFromContext(ctx)
+
+	if !ok {
+		return false, "nobody is authenticated"
+	}
+
+	switch p.roles[user.Role] {
+	case anyone:
+		return true, ""
+	case self:
+		if target == user.ID {
+			return true, ""
+		}
+	}
+
+	return false, p.reason
+}
+
+// Authorize is middleware that checks the policy for the route's pattern,
+// answering 403 with the reason when the authenticated user or API key
+// (see Authenticator.Require, which must run first) lacks the permission
+func Authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target, _ := strconv.Atoi(r.PathValue("id"))
+
+		if ok, reason := allowed(r.Context(), r.Pattern, target); !ok {
+			writeProblem(w, r, http.StatusForbidden, reason)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkRoleChange answers 403 unless the caller may give the user with ID
+// target the role newRole, when it isn't oldRole
+func checkRoleChange(w http.ResponseWriter, r *http.Request, target int, oldRole, newRole string) bool {
+	if newRole == "" || newRole == oldRole {
+		return true
+	}
+
+	if ok, reason := allowed(r.Context(), assignRole, target); !ok {
+		writeProblem(w, r, http.StatusForbidden, reason)
+		return false
+	}
+
+	return true
+}
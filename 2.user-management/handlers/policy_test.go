@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neel07sanghvi/crud-api/models"
+)
+
+// callerID is the ID of the user making the requests; other is someone else
+const (
+	callerID = 7
+	otherID  = 8
+)
+
+// authorizeRouter routes every pattern in policies to a handler answering
+// 200, behind Authorize and a middleware that authenticates as caller
+func authorizeRouter(caller func(context.Context) context.Context) *Router {
+	rt := NewRouter()
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(caller(r.Context())))
+		})
+	}
+	protected := rt.With(authenticate, Authorize)
+
+	for pattern := range policies {
+		method, path, ok := routePattern(pattern)
+
+		if !ok {
+			continue
+		}
+
+		protected.HandleFunc(method, path, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+
+	return rt
+}
+
+// routePattern splits a policy's pattern into method and path, if it is a
+// route rather than a permission such as assignRole
+func routePattern(pattern string) (string, string, bool) {
+	method, path, ok := strings.Cut(pattern, " ")
+	return method, path, ok && strings.HasPrefix(path, "/")
+}
+
+func asUser(role string) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, userContextKey{}, &models.User{ID: callerID, Role: role})
+	}
+}
+
+func asAPIKey(scopes ...string) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, apiKeyContextKey{}, &models.APIKey{ID: "k", Scopes: scopes, ExpiresAt: time.Now().Add(time.Hour)})
+	}
+}
+
+func authorizeStatus(t *testing.T, rt *Router, method, path string) int {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+	return w.Code
+}
+
+// userPath fills the {id} of a route pattern's path with id
+func userPath(path string, id int) string {
+	return strings.Replace(path, "{id}", strconv.Itoa(id), 1)
+}
+
+func TestAuthorizeRoles(t *testing.T) {
+	const (
+		yes = http.StatusOK
+		no  = http.StatusForbidden
+	)
+
+	// What each role gets on its own user and on someone else's
+	type outcome struct{ self, other int }
+
+	tests := []struct {
+		route string
+		roles map[string]outcome
+	}{
+		{"GET /users", map[string]outcome{
+			models.RoleAdmin:   {yes, yes},
+			models.RoleManager: {yes, yes},
+			models.RoleMember:  {no, no},
+		}},
+		{"POST /users", map[string]outcome{
+			models.RoleAdmin:   {yes, yes},
+			models.RoleManager: {yes, yes},
+			models.RoleMember:  {no, no},
+		}},
+		{"GET /users/{id}", map[string]outcome{
+			models.RoleAdmin:   {yes, yes},
+			models.RoleManager: {yes, yes},
+			models.RoleMember:  {yes, no},
+		}},
+		{"PUT /users/{id}", map[string]outcome{
+			models.RoleAdmin:   {yes, yes},
+			models.RoleManager: {yes, yes},
+			models.RoleMember:  {yes, no},
+		}},
+		{"PATCH /users/{id}", map[string]outcome{
+			models.RoleAdmin:   {yes, yes},
+			models.RoleManager: {yes, yes},
+			models.RoleMember:  {yes, no},
+		}},
+		{"DELETE /users/{id}", map[string]outcome{
+			models.RoleAdmin:   {yes, yes},
+			models.RoleManager: {no, no},
+			models.RoleMember:  {no, no},
+		}},
+		{"PUT /users/{id}/password", map[string]outcome{
+			models.RoleAdmin:   {yes, yes},
+			models.RoleManager: {yes, no},
+			models.RoleMember:  {yes, no},
+		}},
+		{"GET /api-keys", map[string]outcome{
+			models.RoleAdmin:   {yes, yes},
+			models.RoleManager: {no, no},
+			models.RoleMember:  {no, no},
+		}},
+		{"POST /api-keys", map[string]outcome{
+			models.RoleAdmin:   {yes, yes},
+			models.RoleManager: {no, no},
+			models.RoleMember:  {no, no},
+		}},
+		{"DELETE /api-keys/{id}", map[string]outcome{
+			models.RoleAdmin:   {yes, yes},
+			models.RoleManager: {no, no},
+			models.RoleMember:  {no, no},
+		}},
+	}
+
+	tested := make(map[string]bool)
+
+	for _, tt := range tests {
+		tested[tt.route] = true
+		method, path, _ := strings.Cut(tt.route, " ")
+
+		for _, role := range models.Roles {
+			want, ok := tt.roles[role]
+
+			if !ok {
+				t.Fatalf("%s: no outcome for role %s", tt.route, role)
+			}
+
+			rt := authorizeRouter(asUser(role))
+
+			t.Run(tt.route+"/"+role+"/self", func(t *testing.T) {
+				if got := authorizeStatus(t, rt, method, userPath(path, callerID)); got != want.self {
+					t.Errorf("status %d, want %d", got, want.self)
+				}
+			})
+
+			t.Run(tt.route+"/"+role+"/other", func(t *testing.T) {
+				if got := authorizeStatus(t, rt, method, userPath(path, otherID)); got != want.other {
+					t.Errorf("status %d, want %d", got, want.other)
+				}
+			})
+		}
+	}
+
+	for pattern := range policies {
+		if _, _, ok := routePattern(pattern); ok && !tested[pattern] {
+			t.Errorf("the policy for %s has no test", pattern)
+		}
+	}
+}
+
+func TestAuthorizeAPIKeyScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		route  string
+		want   int
+	}{
+		{"read lists users", []string{models.ScopeUsersRead}, "GET /users", http.StatusOK},
+		{"read gets any user", []string{models.ScopeUsersRead}, "GET /users/{id}", http.StatusOK},
+		{"read can't create", []string{models.ScopeUsersRead}, "POST /users", http.StatusForbidden},
+		{"read can't update", []string{models.ScopeUsersRead}, "PUT /users/{id}", http.StatusForbidden},
+		{"write creates", []string{models.ScopeUsersWrite}, "POST /users", http.StatusOK},
+		{"write updates", []string{models.ScopeUsersWrite}, "PUT /users/{id}", http.StatusOK},
+		{"write patches", []string{models.ScopeUsersWrite}, "PATCH /users/{id}", http.StatusOK},
+		{"write can't read", []string{models.ScopeUsersWrite}, "GET /users", http.StatusForbidden},
+		{"write can't delete", []string{models.ScopeUsersWrite}, "DELETE /users/{id}", http.StatusForbidden},
+		{"delete deletes", []string{models.ScopeUsersDelete}, "DELETE /users/{id}", http.StatusOK},
+		{"every scope can't set passwords", models.Scopes, "PUT /users/{id}/password", http.StatusForbidden},
+		{"every scope can't list keys", models.Scopes, "GET /api-keys", http.StatusForbidden},
+		{"every scope can't create keys", models.Scopes, "POST /api-keys", http.StatusForbidden},
+		{"every scope can't revoke keys", models.Scopes, "DELETE /api-keys/{id}", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, path, _ := strings.Cut(tt.route, " ")
+			rt := authorizeRouter(asAPIKey(tt.scopes...))
+
+			if got := authorizeStatus(t, rt, method, userPath(path, otherID)); got != tt.want {
+				t.Errorf("%s with scopes %v: status %d, want %d", tt.route, tt.scopes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeUnauthenticated(t *testing.T) {
+	rt := authorizeRouter(func(ctx context.Context) context.Context { return ctx })
+
+	if got := authorizeStatus(t, rt, http.MethodGet, "/users"); got != http.StatusForbidden {
+		t.Errorf("status %d, want %d", got, http.StatusForbidden)
+	}
+}
+
+func TestAllowedUnknownPermission(t *testing.T) {
+	ctx := asUser(models.RoleAdmin)(context.Background())
+
+	if ok, _ := allowed(ctx, "GET /nowhere", 0); ok {
+		t.Error("a permission without a policy was allowed")
+	}
+}
+
+func TestCheckRoleChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		caller   func(context.Context) context.Context
+		target   int
+		old, new string
+		want     bool
+	}{
+		{"admin promotes someone", asUser(models.RoleAdmin), otherID, models.RoleMember, models.RoleManager, true},
+		{"admin demotes themselves", asUser(models.RoleAdmin), callerID, models.RoleAdmin, models.RoleMember, true},
+		{"manager can't promote", asUser(models.RoleManager), otherID, models.RoleMember, models.RoleManager, false},
+		{"manager can't promote themselves", asUser(models.RoleManager), callerID, models.RoleManager, models.RoleAdmin, false},
+		{"member can't promote themselves", asUser(models.RoleMember), callerID, models.RoleMember, models.RoleAdmin, false},
+		{"member keeps their role", asUser(models.RoleMember), callerID, models.RoleMember, models.RoleMember, true},
+		{"no role given keeps it", asUser(models.RoleMember), callerID, models.RoleMember, "", true},
+		{"API keys can't assign roles", asAPIKey(models.Scopes...), otherID, models.RoleMember, models.RoleAdmin, false},
+		{"API keys can leave roles alone", asAPIKey(models.ScopeUsersWrite), otherID, models.RoleMember, models.RoleMember, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPut, "/users/"+strconv.Itoa(tt.target), nil)
+			r = r.WithContext(tt.caller(r.Context()))
+
+			if got := checkRoleChange(w, r, tt.target, tt.old, tt.new); got != tt.want {
+				t.Fatalf("checkRoleChange(%q -> %q) = %v, want %v", tt.old, tt.new, got, tt.want)
+			}
+
+			if !tt.want && w.Code != http.StatusForbidden {
+				t.Errorf("status %d, want %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/neel07sanghvi/crud-api/ratelimit"
+)
+
+// FailedAuth is the key in NewRateLimiter's routes for the limit on
+// requests failing authentication, per IP address
+const FailedAuth = "failed auth"
+
+// RateLimiter limits each client's requests, per route. A route with its
+// own limit has its own buckets; the other routes share the default's.
+type RateLimiter struct {
+	fallback   *ratelimit.Limiter
+	routes     map[string]*ratelimit.Limiter // by ServeMux pattern
+	failedAuth *ratelimit.Limiter
+}
+
+// NewRateLimiter limits requests to fallback, except on the routes in
+// routes, keyed by their ServeMux pattern such as "POST /users". Failed
+// authentication is limited to routes[FailedAuth], or else to fallback.
+func NewRateLimiter(fallback ratelimit.Limit, routes map[string]ratelimit.Limit) *RateLimiter {
+	rl := &RateLimiter{
+		fallback: ratelimit.New(fallback),
+		routes:   make(map[string]*ratelimit.Limiter, len(routes)),
+	}
+
+	for pattern, limit := range routes {
+		if pattern == FailedAuth {
+			rl.failedAuth = ratelimit.New(limit)
+			continue
+		}
+
+		rl.routes[pattern] = ratelimit.New(limit)
+	}
+
+	if rl.failedAuth == nil {
+		rl.failedAuth = ratelimit.New(fallback)
+	}
+
+	return rl
+}
+
+// Limit is middleware that answers 429 with Retry-After once a client has
+// used up its requests, and tells every client where it stands in
+// RateLimit-* headers. Clients are told apart by API key, then user, then
+// IP address: after Authenticator.Require it limits each user, and
+// LimitFailedAuth is what limits the requests Require turns away.
+func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter, ok := rl.routes[r.Pattern]
+
+		if !ok {
+			limiter = rl.fallback
+		}
+
+		if !allow(w, r, limiter.Allow(client(r), time.Now())) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LimitFailedAuth is middleware to run before Authenticator.Require. Every
+// 401 takes a token from the caller's IP address, and once those are used
+// up the address is answered 429 before its credentials are looked at, so
+// tokens and API keys can't be guessed at the speed of the server. Callers
+// that do authenticate aren't counted, so users behind one address don't
+// use up each other's requests.
+func (rl *RateLimiter) LimitFailedAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+
+		if !allow(w, r, rl.failedAuth.Peek(ip, time.Now())) {
+			return
+		}
+
+		next.ServeHTTP(&failedAuthWriter{ResponseWriter: w, limiter: rl.failedAuth, ip: ip}, r)
+	})
+}
+
+// failedAuthWriter takes a token from ip when the response is a 401, and
+// reports what is left in the RateLimit-* headers
+type failedAuthWriter struct {
+	http.ResponseWriter
+	limiter     *ratelimit.Limiter
+	ip          string
+	wroteHeader bool
+}
+
+func (w *failedAuthWriter) WriteHeader(status int) {
+	if !w.wroteHeader && status == http.StatusUnauthorized {
+		setRateLimitHeaders(w.Header(), w.limiter.Allow(w.ip, time.Now()))
+	}
+
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *failedAuthWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *failedAuthWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// allow sets the RateLimit-* headers for result, answering 429 if it
+// doesn't allow the request
+func allow(w http.ResponseWriter, r *http.Request, result ratelimit.Result) bool {
+	setRateLimitHeaders(w.Header(), result)
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", seconds(result.RetryAfter))
+		writeProblem(w, r, http.StatusTooManyRequests, "too many requests: try again in "+seconds(result.RetryAfter)+"s")
+		return false
+	}
+
+	return true
+}
+
+func setRateLimitHeaders(h http.Header, result ratelimit.Result) {
+	h.Set("RateLimit-Policy", strconv.Itoa(result.Limit.Requests)+";w="+seconds(result.Limit.Per))
+	h.Set("RateLimit-Limit", strconv.Itoa(result.Limit.Requests))
+	h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	h.Set("RateLimit-Reset", seconds(result.Reset))
+}
+
+// client identifies who a request counts against
+func client(r *http.Request) string {
+	if key, ok := APIKeyFromContext(r.Context()); ok {
+		return "key:" + key.ID
+	}
+
+	if user, ok := UserFromContext(r.Context()); ok {
+		return "user:" + strconv.Itoa(user.ID)
+	}
+
+	return clientIP(r)
+}
+
+// clientIP is the client key of a request's IP address
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// seconds rounds d up to whole seconds, as the headers want them
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/neel07sanghvi/crud-api/ratelimit"
+)
+
+// failedAuthRouter serves GET /users behind LimitFailedAuth and a stand-in
+// for Authenticator.Require that accepts the Authorization "good"
+func failedAuthRouter(failures int) *Router {
+	rl := NewRateLimiter(ratelimit.Limit{Requests: 100, Per: time.Minute}, map[string]ratelimit.Limit{
+		FailedAuth: {Requests: failures, Per: time.Minute},
+	})
+	require := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "good" {
+				writeProblem(w, r, http.StatusUnauthorized, "bad credentials")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	rt := NewRouter()
+	rt.With(rl.LimitFailedAuth, require, rl.Limit).HandleFunc(http.MethodGet, "/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return rt
+}
+
+func failedAuthRequest(rt *Router, auth, addr string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.RemoteAddr = addr
+	r.Header.Set("Authorization", auth)
+	rt.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestLimitFailedAuth(t *testing.T) {
+	rt := failedAuthRouter(3)
+	const addr = "192.0.2.1:1234"
+
+	for i, want := range []string{"2", "1", "0"} {
+		w := failedAuthRequest(rt, "bad", addr)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status %d, want %d", i+1, w.Code, http.StatusUnauthorized)
+		}
+
+		if got := w.Header().Get("RateLimit-Remaining"); got != want {
+			t.Errorf("failure %d: RateLimit-Remaining %q, want %q", i+1, got, want)
+		}
+	}
+
+	tests := []struct {
+		name string
+		auth string
+		addr string
+		want int
+	}{
+		{"bad credentials", "bad", addr, http.StatusTooManyRequests},
+		{"good credentials from the same address", "good", addr, http.StatusTooManyRequests},
+		{"another address", "bad", "192.0.2.2:1234", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := failedAuthRequest(rt, tt.auth, tt.addr)
+
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d", w.Code, tt.want)
+			}
+
+			if tt.want == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Error("no Retry-After on a 429")
+			}
+		})
+	}
+}
+
+func TestLimitFailedAuthSkipsAuthenticated(t *testing.T) {
+	rt := failedAuthRouter(1)
+	const addr = "192.0.2.1:1234"
+
+	for range 5 {
+		w := failedAuthRequest(rt, "good", addr)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d, want %d", w.Code, http.StatusOK)
+		}
+
+		// The user's own bucket, from Limit, is the one reported
+		if got := w.Header().Get("RateLimit-Limit"); got != "100" {
+			t.Errorf("RateLimit-Limit %q, want %q", got, "100")
+		}
+	}
+
+	if w := failedAuthRequest(rt, "bad", addr); w.Code != http.StatusUnauthorized {
+		t.Errorf("first failure: status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Router wraps http.ServeMux so that every error it produces on its own,
+// an unknown path (404) or a method the path doesn't support (405), is
+// answered with a problem instead of ServeMux's plain text
+type Router struct {
+	mux        *http.ServeMux
+	methods    map[string][]string
+	middleware []func(http.Handler) http.Handler
+}
+
+func NewRouter() *Router {
+	rt := &Router{
+		mux:     http.NewServeMux(),
+		methods: make(map[string][]string),
+	}
+
+	rt.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusNotFound, "no such resource")
+	})
+
+	return rt
+}
+
+// With returns a router that adds routes to the same mux as rt, but wraps
+// their handlers in middleware (after any of rt's own), e.g. to require
+// authentication for some routes only
+func (rt *Router) With(middleware ...func(http.Handler) http.Handler) *Router {
+	return &Router{
+		mux:        rt.mux,
+		methods:    rt.methods,
+		middleware: append(slices.Clip(rt.middleware), middleware...),
+	}
+}
+
+// HandleFunc routes requests for method and path, a ServeMux pattern such as /users/{id}
+func (rt *Router) HandleFunc(method, path string, handler http.HandlerFunc) {
+	var h http.Handler = handler
+
+	for _, mw := range slices.Backward(rt.middleware) {
+		h = mw(h)
+	}
+
+	rt.mux.Handle(method+" "+path, h)
+
+	if _, known := rt.methods[path]; !known {
+		// Without a method this pattern is less specific than the ones with
+		// methods, so it only sees the methods that aren't routed
+		rt.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", strings.Join(rt.methods[path], ", "))
+			writeProblem(w, r, http.StatusMethodNotAllowed, r.Method+" is not supported on "+r.URL.Path)
+		})
+	}
+
+	rt.methods[path] = append(rt.methods[path], method)
+
+	// ServeMux answers HEAD with the GET handler
+	if method == http.MethodGet && !slices.Contains(rt.methods[path], http.MethodHead) {
+		rt.methods[path] = append(rt.methods[path], http.MethodHead)
+	}
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -11,119 +14,242 @@ import (
 )
 
 type UserHandler struct {
-	storage *storage.UserStorage
+	storage storage.UserRepository
 }
 
-func New(storage *storage.UserStorage) *UserHandler {
+func New(storage storage.UserRepository) *UserHandler {
 	return &UserHandler{storage: storage}
 }
 
-func (h *UserHandler) HandleUsers(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	switch r.Method {
-	case "GET":
-		h.handleGet(w, r)
-	case "POST":
-		h.handlePost(w, r)
-	case "PUT":
-		h.handlePut(w, r)
-	case "DELETE":
-		h.handleDelete(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+func (h *UserHandler) Register(rt *Router) {
+	rt.HandleFunc(http.MethodGet, "/users", h.handleList)
+	rt.HandleFunc(http.MethodPost, "/users", h.handleCreate)
+	rt.HandleFunc(http.MethodGet, "/users/{id}", h.handleGet)
+	rt.HandleFunc(http.MethodPut, "/users/{id}", h.handleUpdate)
+	rt.HandleFunc(http.MethodPatch, "/users/{id}", h.handlePatch)
+	rt.HandleFunc(http.MethodDelete, "/users/{id}", h.handleDelete)
+}
+
+// handleList returns one page of users. When there are more, the Link
+// header points to the next page with the same parameters.
+func (h *UserHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	query, err := parseUserQuery(r.URL.Query())
+
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.storage.ListUsers(r.Context(), query)
+
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
 	}
+
+	if page.NextCursor != "" {
+		next := r.URL.Query()
+		next.Set("cursor", page.NextCursor)
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode()))
+	}
+
+	writeJSON(w, http.StatusOK, page.Users)
 }
 
 func (h *UserHandler) handleGet(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path
+	id, ok := userID(w, r)
 
-	if path == "/users" {
-		users := h.storage.GetAllUsers()
-		json.NewEncoder(w).Encode(users)
-	} else {
-		id, err := h.extractIDFromPath(path)
+	if !ok {
+		return
+	}
 
-		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	user, err := h.storage.GetUserByID(r.Context(), id)
 
-			return
-		}
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
 
-		json.NewEncoder(w).Encode(id)
+	if header := r.Header.Get("If-None-Match"); header != "" && matchETag(header, etag(user), true) {
+		w.Header().Set("ETag", etag(user))
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
+
+	writeUser(w, http.StatusOK, user)
 }
 
-func (h *UserHandler) handlePost(w http.ResponseWriter, r *http.Request) {
-	var req models.CreateUserRequest
+func (h *UserHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeUserRequest(w, r)
+
+	if !ok {
+		return
+	}
+
+	// A user created without a password can't log in until they are given one
+	var hash string
+
+	if req.Password != "" {
+		var invalid *InvalidParam
+
+		if hash, invalid = hashPassword(req.Password); invalid != nil {
+			writeProblem(w, r, http.StatusBadRequest, "the user is invalid", *invalid)
+			return
+		}
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if !checkRoleChange(w, r, 0, models.RoleMember, req.Role) {
 		return
 	}
 
-	if req.Name == "" || req.Email == "" {
-		http.Error(w, "Name and email are required", http.StatusBadRequest)
+	user, err := h.storage.CreateUser(r.Context(), req.Fields(), hash)
+
+	if err != nil {
+		writeStorageError(w, r, err)
 		return
 	}
 
-	user := h.storage.CreateUser(req.Name, req.Email)
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(user)
+	w.Header().Set("Location", "/users/"+strconv.Itoa(user.ID))
+	writeUser(w, http.StatusCreated, user)
 }
 
-func (h *UserHandler) handlePut(w http.ResponseWriter, r *http.Request) {
-	id, err := h.extractIDFromPath(r.URL.Path)
+func (h *UserHandler) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	id, ok := userID(w, r)
 
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	if !ok {
 		return
 	}
 
-	var req models.CreateUserRequest
+	req, ok := decodeUserRequest(w, r)
+
+	if !ok {
+		return
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if req.Password != "" {
+		writeProblem(w, r, http.StatusBadRequest, "the user is invalid", InvalidParam{Name: "password", Reason: "can only be changed with PUT /users/{id}/password"})
 		return
 	}
 
-	if req.Name == "" || req.Email == "" {
-		http.Error(w, "Name and email are required", http.StatusBadRequest)
+	if req.Role != "" {
+		current, err := h.storage.GetUserByID(r.Context(), id)
+
+		if err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+
+		if !checkRoleChange(w, r, id, current.Role, req.Role) {
+			return
+		}
+	}
+
+	version, ok := h.ifMatch(w, r, id)
+
+	if !ok {
 		return
 	}
 
-	user, exists := h.storage.UpdateUser(id, req.Name, req.Email)
+	user, err := h.storage.UpdateUser(r.Context(), id, version, req.Fields())
 
-	if !exists {
-		http.Error(w, "User not found", http.StatusNotFound)
+	if err != nil {
+		writeStorageError(w, r, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(user)
+	writeUser(w, http.StatusOK, user)
 }
 
 func (h *UserHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
-	id, err := h.extractIDFromPath(r.URL.Path)
+	id, ok := userID(w, r)
 
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	if !ok {
+		return
+	}
+
+	version, ok := h.ifMatch(w, r, id)
+
+	if !ok {
 		return
 	}
 
-	if !h.storage.DeleteUser(id) {
-		http.Error(w, "User not found", http.StatusNotFound)
+	if err := h.storage.DeleteUser(r.Context(), id, version); err != nil {
+		writeStorageError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *UserHandler) extractIDFromPath(path string) (int, error) {
-	parts := strings.Split(path, "/")
+// writeStorageError turns a repository error into a problem, hiding the
+// details of unexpected failures from the client
+func writeStorageError(w http.ResponseWriter, r *http.Request, err error) {
+	var conflict *storage.ConflictError
+
+	switch {
+	case errors.As(err, &conflict):
+		writeProblem(w, r, http.StatusConflict, err.Error(), InvalidParam{Name: conflict.Field, Reason: "is already taken"})
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrAPIKeyNotFound):
+		writeProblem(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, storage.ErrConflict):
+		writeProblem(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, storage.ErrVersionMismatch):
+		writeProblem(w, r, http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, storage.ErrInvalidQuery):
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "storage failed", "request_id", RequestIDFromContext(r.Context()), "method", r.Method, "path", r.URL.Path, "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "the user storage failed")
+	}
+}
+
+// userID reads the {id} path wildcard, answering 400 when it isn't a number
+func userID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid user ID "+strconv.Quote(r.PathValue("id")))
+		return 0, false
+	}
+
+	return id, true
+}
+
+func decodeUserRequest(w http.ResponseWriter, r *http.Request) (models.CreateUserRequest, bool) {
+	var req models.CreateUserRequest
+
+	if !readJSON(w, r, &req) {
+		return req, false
+	}
+
+	if invalid := validateUser(&req); len(invalid) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, "the user is invalid", invalid...)
+		return req, false
+	}
+
+	return req, true
+}
+
+// validateUser trims the name and normalises the email of req, reporting
+// the fields that are invalid
+func validateUser(req *models.CreateUserRequest) []InvalidParam {
+	var invalid []InvalidParam
+
+	if req.Name = strings.TrimSpace(req.Name); req.Name == "" {
+		invalid = append(invalid, InvalidParam{Name: "name", Reason: "is required"})
+	}
+
+	email, err := models.NormalizeEmail(req.Email)
+
+	if err != nil {
+		invalid = append(invalid, InvalidParam{Name: "email", Reason: err.Error()})
+	}
+
+	req.Email = email
 
-	if len(parts) < 3 {
-		return 0, http.ErrNotSupported
+	if req.Role != "" && !slices.Contains(models.Roles, req.Role) {
+		invalid = append(invalid, InvalidParam{Name: "role", Reason: "must be one of " + strings.Join(models.Roles, ", ")})
 	}
 
-	return strconv.Atoi(parts[2])
+	return invalid
 }
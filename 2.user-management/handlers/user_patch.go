@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/neel07sanghvi/crud-api/models"
+	"github.com/neel07sanghvi/crud-api/storage"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// readOnlyFields are the fields of a user a patch may not change
+var readOnlyFields = []string{"id", "created_at", "version"}
+
+// handlePatch changes some fields of a user with a merge patch (RFC 7396)
+// or a JSON patch (RFC 6902). The patch is applied to a copy of the user,
+// so it is either applied as a whole or not at all: a malformed patch is
+// 400, one that doesn't fit the user (a failed test, a missing path) is
+// 409, one whose result isn't a valid user is 422, and a stale If-Match
+// is 412.
+func (h *UserHandler) handlePatch(w http.ResponseWriter, r *http.Request) {
+	id, ok := userID(w, r)
+
+	if !ok {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if mediaType != mergePatchType && mediaType != jsonPatchType {
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "the patch must be "+mergePatchType+" or "+jsonPatchType)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "reading the body: "+err.Error())
+		return
+	}
+
+	user, err := h.storage.GetUserByID(r.Context(), id)
+
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+
+	if !checkIfMatch(w, r, user) {
+		return
+	}
+
+	original := userDocument(user)
+	var doc any
+
+	if mediaType == mergePatchType {
+		patch, err := decodeJSON(body)
+
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+
+		doc = mergePatch(userDocument(user), patch)
+	} else {
+		doc, err = applyJSONPatch(userDocument(user), body)
+
+		if errors.Is(err, errInvalidPatch) {
+			writeProblem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err != nil {
+			writeProblem(w, r, http.StatusConflict, err.Error())
+			return
+		}
+	}
+
+	fields, ok := doc.(map[string]any)
+
+	if !ok {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "the patched user must be a JSON object")
+		return
+	}
+
+	req, invalid := patchedUser(original, fields)
+
+	if len(invalid) > 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "the patched user is invalid", invalid...)
+		return
+	}
+
+	if !checkRoleChange(w, r, id, user.Role, req.Role) {
+		return
+	}
+
+	// The patch was applied to this version, so it mustn't overwrite a change
+	// made since, whether or not the client asked for If-Match
+	updated, err := h.storage.UpdateUser(r.Context(), id, user.Version, req.Fields())
+
+	if r.Header.Get("If-Match") == "" && errors.Is(err, storage.ErrVersionMismatch) {
+		writeProblem(w, r, http.StatusConflict, "the user was changed by another request while it was being patched; try again")
+		return
+	}
+
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeUser(w, http.StatusOK, updated)
+}
+
+// userDocument returns user as a generic JSON document for patching
+func userDocument(user *models.User) map[string]any {
+	data, _ := json.Marshal(user)
+	doc, _ := decodeJSON(data)
+
+	return doc.(map[string]any)
+}
+
+// patchedUser checks a patched user document against the original: the
+// read-only fields must be unchanged, there may be no other fields than a
+// user's, and the name, email and role must be valid
+func patchedUser(original, fields map[string]any) (models.CreateUserRequest, []InvalidParam) {
+	var req models.CreateUserRequest
+	var invalid []InvalidParam
+
+	for _, name := range readOnlyFields {
+		if _, ok := fields[name]; !ok {
+			invalid = append(invalid, InvalidParam{Name: name, Reason: "is read-only"})
+		}
+	}
+
+	for name, value := range fields {
+		switch name {
+		case "name", "email", "role":
+			s, ok := value.(string)
+
+			switch {
+			case !ok:
+				invalid = append(invalid, InvalidParam{Name: name, Reason: "must be a string"})
+			case name == "name":
+				req.Name = s
+			case name == "email":
+				req.Email = s
+			default:
+				req.Role = s
+			}
+		default:
+			if !slices.Contains(readOnlyFields, name) {
+				invalid = append(invalid, InvalidParam{Name: name, Reason: "is not a field of a user"})
+			} else if !equalJSON(value, original[name]) {
+				invalid = append(invalid, InvalidParam{Name: name, Reason: "is read-only"})
+			}
+		}
+	}
+
+	if len(invalid) == 0 {
+		invalid = validateUser(&req)
+
+		// A patch that removes the role leaves none, which storage would
+		// take as keeping the old one
+		if req.Role == "" {
+			invalid = append(invalid, InvalidParam{Name: "role", Reason: "is required"})
+		}
+	}
+
+	sort.Slice(invalid, func(i, j int) bool { return invalid[i].Name < invalid[j].Name })
+
+	return req, invalid
+}
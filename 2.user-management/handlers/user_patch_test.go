@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/neel07sanghvi/crud-api/models"
+)
+
+func TestPatchedUserRequiredFields(t *testing.T) {
+	user := &models.User{ID: 1, Name: "Ada", Email: "ada@example.com", Role: models.RoleManager, CreatedAt: time.Now(), Version: 1}
+
+	tests := []struct {
+		name  string
+		merge string // a merge patch, or else
+		json  string // a JSON patch
+		want  []string
+	}{
+		{name: "merge keeps the role", merge: `{"name":"Ada L"}`},
+		{name: "merge changes the role", merge: `{"role":"member"}`},
+		{name: "merge removes the role", merge: `{"role":null}`, want: []string{"role"}},
+		{name: "merge empties the role", merge: `{"role":""}`, want: []string{"role"}},
+		{name: "merge removes the name", merge: `{"name":null}`, want: []string{"name"}},
+		{name: "merge removes the email", merge: `{"email":null}`, want: []string{"email"}},
+		{name: "JSON patch removes the role", json: `[{"op":"remove","path":"/role"}]`, want: []string{"role"}},
+		{name: "JSON patch replaces the role", json: `[{"op":"replace","path":"/role","value":"admin"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc any
+
+			if tt.merge != "" {
+				patch, err := decodeJSON([]byte(tt.merge))
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				doc = mergePatch(userDocument(user), patch)
+			} else {
+				var err error
+				doc, err = applyJSONPatch(userDocument(user), []byte(tt.json))
+
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, invalid := patchedUser(userDocument(user), doc.(map[string]any))
+			var got []string
+
+			for _, p := range invalid {
+				got = append(got, p.Name)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("invalid params %v, want %v", invalid, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neel07sanghvi/crud-api/storage"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 100
+)
+
+var listParams = []string{"limit", "cursor", "sort", "email", "name_contains", "created_after"}
+
+// parseUserQuery reads the query string of GET /users. Unknown parameters
+// are rejected too, so a typo doesn't silently return everything.
+func parseUserQuery(values url.Values) (storage.UserQuery, error) {
+	q := storage.UserQuery{Limit: defaultLimit}
+
+	for name, v := range values {
+		if !slices.Contains(listParams, name) {
+			return q, fmt.Errorf("unknown query parameter %q (want one of: %s)", name, strings.Join(listParams, ", "))
+		}
+
+		if len(v) > 1 {
+			return q, fmt.Errorf("query parameter %q is given %d times", name, len(v))
+		}
+	}
+
+	if s := values.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+
+		if err != nil || limit < 1 || limit > maxLimit {
+			return q, fmt.Errorf("limit must be a number from 1 to %d, got %q", maxLimit, s)
+		}
+
+		q.Limit = limit
+	}
+
+	if s := values.Get("sort"); s != "" {
+		sort, err := parseSort(s)
+
+		if err != nil {
+			return q, err
+		}
+
+		q.Sort = sort
+	}
+
+	if s := values.Get("created_after"); s != "" {
+		t, err := parseTime(s)
+
+		if err != nil {
+			return q, err
+		}
+
+		q.CreatedAfter = t
+	}
+
+	q.Email = values.Get("email")
+	q.NameContains = values.Get("name_contains")
+	q.Cursor = values.Get("cursor")
+
+	return q, nil
+}
+
+// parseSort reads a list such as name,-created_at; a leading - sorts descending
+func parseSort(s string) ([]storage.SortField, error) {
+	var fields []storage.SortField
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ",") {
+		name, desc := strings.CutPrefix(strings.TrimSpace(part), "-")
+
+		if !slices.Contains(storage.SortFields, name) {
+			return nil, fmt.Errorf("cannot sort by %q (want one of: %s, each optionally prefixed with -)", name, strings.Join(storage.SortFields, ", "))
+		}
+
+		if seen[name] {
+			return nil, fmt.Errorf("sort field %q is given twice", name)
+		}
+
+		seen[name] = true
+		fields = append(fields, storage.SortField{Name: name, Desc: desc})
+	}
+
+	return fields, nil
+}
+
+// parseTime accepts an RFC 3339 timestamp or a date, meaning midnight UTC
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("created_after must be an RFC 3339 timestamp or a date like 2024-01-15, got %q", s)
+}
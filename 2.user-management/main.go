@@ -1,38 +1,203 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/neel07sanghvi/crud-api/auth"
 	"github.com/neel07sanghvi/crud-api/handlers"
+	"github.com/neel07sanghvi/crud-api/models"
+	"github.com/neel07sanghvi/crud-api/ratelimit"
 	"github.com/neel07sanghvi/crud-api/storage"
 )
 
 func main() {
-	userStorage := storage.New()
+	backend := flag.String("storage", "memory", "where to keep users: memory or file")
+	dataFile := flag.String("data", "users.json", "JSON file used by the file storage")
+	keysFile := flag.String("keys", "api_keys.json", "JSON file for API keys used by the file storage")
+	defaultLimit := ratelimit.Limit{Requests: 120, Per: time.Minute}
+	routeLimits := map[string]ratelimit.Limit{
+		"POST /auth/login":  {Requests: 10, Per: time.Minute},
+		"POST /users":       {Requests: 20, Per: time.Minute},
+		handlers.FailedAuth: {Requests: 10, Per: time.Minute},
+	}
 
+	flag.Func("rate-limit", `a route's rate limit as "pattern=requests/period", e.g. "POST /users=20/1m", "default=120/1m" for the other routes, or "failed auth=10/1m" for requests failing authentication; repeatable`, func(s string) error {
+		pattern, limit, err := parseRateLimit(s)
+
+		if err != nil {
+			return err
+		}
+
+		if pattern == "default" {
+			defaultLimit = limit
+		} else {
+			routeLimits[pattern] = limit
+		}
+
+		return nil
+	})
+	flag.Parse()
+
+	// Logs go to stderr as JSON, one object per line; the standard log
+	// package writes through the same handler
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	slog.SetDefault(logger)
+
+	userStorage, keyStorage, err := newStorage(*backend, *dataFile, *keysFile)
+
+	if err != nil {
+		log.Fatalf("Cannot set up storage: %v", err)
+	}
+
+	if err := bootstrapUser(userStorage, os.Getenv("ADMIN_EMAIL"), os.Getenv("ADMIN_PASSWORD")); err != nil {
+		log.Fatalf("Cannot create the first user: %v", err)
+	}
+
+	authenticator := handlers.NewAuthenticator(userStorage, keyStorage, signingKey())
 	userHandler := handlers.New(userStorage)
+	keyHandler := handlers.NewAPIKeyHandler(keyStorage)
+
+	limiter := handlers.NewRateLimiter(defaultLimit, routeLimits)
 
-	http.HandleFunc("/users", userHandler.HandleUsers)
-	http.HandleFunc("/users/", userHandler.HandleUsers)
+	router := handlers.NewRouter()
+	public := router.With(limiter.Limit)
+	protected := router.With(limiter.LimitFailedAuth, authenticator.Require, limiter.Limit, handlers.Authorize)
+	authenticator.Register(public, protected)
+	userHandler.Register(protected)
+	keyHandler.Register(protected)
 
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	router.HandleFunc(http.MethodGet, "/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		fmt.Fprint(w, `{"status": "healthy"}`)
 	})
 
 	port := "8080"
 
-	fmt.Printf("Server starting on port %s\n", port)
+	fmt.Printf("Server starting on port %s with %s storage\n", port, *backend)
 	fmt.Println("Available endpoints:")
-	fmt.Println("  GET    /health        - Health check")
-	fmt.Println("  GET    /users         - Get all users")
-	fmt.Println("  GET    /users/1       - Get user by ID")
-	fmt.Println("  POST   /users         - Create new user")
-	fmt.Println("  PUT    /users/1       - Update user")
-	fmt.Println("  DELETE /users/1       - Delete user")
+	fmt.Println("  GET    /health            - Health check")
+	fmt.Println("  POST   /auth/login        - Log in for an access and refresh token")
+	fmt.Println("  POST   /auth/refresh      - Swap a refresh token for new tokens")
+	fmt.Println("  POST   /auth/logout       - Revoke a refresh token")
+	fmt.Println("  GET    /users             - Get all users")
+	fmt.Println("  GET    /users/1           - Get user by ID")
+	fmt.Println("  POST   /users             - Create new user")
+	fmt.Println("  PUT    /users/1           - Update user")
+	fmt.Println("  PATCH  /users/1           - Patch user (merge patch or JSON patch)")
+	fmt.Println("  DELETE /users/1           - Delete user")
+	fmt.Println("  PUT    /users/1/password  - Change a password")
+	fmt.Println("  GET    /api-keys          - List API keys (admins)")
+	fmt.Println("  POST   /api-keys          - Create an API key (admins)")
+	fmt.Println("  DELETE /api-keys/id       - Revoke an API key (admins)")
+	fmt.Println("  (/users and /api-keys need an Authorization: Bearer <access token> or ApiKey <key> header)")
 	fmt.Println()
 
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	server := &http.Server{
+		Addr: ":" + port,
+		// The first middleware runs first: every request gets its ID before
+		// it is logged, and a panic is answered before the log records it
+		Handler:  handlers.Chain(router, handlers.RequestID, handlers.AccessLog(logger), handlers.Recover(logger)),
+		ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+
+	log.Fatal(server.ListenAndServe())
+}
+
+// parseRateLimit reads a -rate-limit flag
+func parseRateLimit(s string) (string, ratelimit.Limit, error) {
+	pattern, value, ok := strings.Cut(s, "=")
+
+	if !ok || pattern == "" {
+		return "", ratelimit.Limit{}, fmt.Errorf("%q is not pattern=requests/period", s)
+	}
+
+	limit, err := ratelimit.ParseLimit(value)
+
+	return pattern, limit, err
+}
+
+// signingKey returns the key access tokens are signed with, from
+// JWT_SECRET. Without it a random key is used, so tokens don't survive a restart.
+func signingKey() []byte {
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		if len(secret) < 32 {
+			log.Fatal("JWT_SECRET must have at least 32 characters")
+		}
+
+		return []byte(secret)
+	}
+
+	log.Println("JWT_SECRET is not set; using a random key, so tokens are invalid after a restart")
+
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	return key
+}
+
+// bootstrapUser creates an admin with email and password unless it exists,
+// so that there is someone to log in as on an empty storage
+func bootstrapUser(repo storage.UserRepository, email, password string) error {
+	if email == "" && password == "" {
+		return nil
+	}
+
+	if len(password) < auth.MinPasswordLength {
+		return fmt.Errorf("ADMIN_PASSWORD must have at least %d characters", auth.MinPasswordLength)
+	}
+
+	email, err := models.NormalizeEmail(email)
+
+	if err != nil {
+		return fmt.Errorf("ADMIN_EMAIL %s", err)
+	}
+
+	ctx := context.Background()
+
+	if _, err := repo.GetUserByEmail(ctx, email); !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	hash, err := auth.HashPassword(password)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.CreateUser(ctx, models.UserFields{Name: "Admin", Email: email, Role: models.RoleAdmin}, hash)
+
+	return err
+}
+
+func newStorage(backend, dataFile, keysFile string) (storage.UserRepository, storage.APIKeyRepository, error) {
+	switch backend {
+	case "memory":
+		return storage.New(), storage.NewAPIKeyStorage(), nil
+	case "file":
+		users, err := storage.NewFileStorage(dataFile)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		keys, err := storage.NewFileAPIKeyStorage(keysFile)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return users, keys, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage %q (want memory or file)", backend)
+	}
 }
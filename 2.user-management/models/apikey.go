@@ -0,0 +1,41 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Scopes an API key can have. Each route needs one; see the policies in
+// the handlers package.
+const (
+	ScopeUsersRead   = "users:read"
+	ScopeUsersWrite  = "users:write"
+	ScopeUsersDelete = "users:delete"
+)
+
+var Scopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeUsersDelete}
+
+// APIKey lets a service call the API without a user logging in. The key
+// itself is only shown when it is created; only a hash of its secret is kept.
+type APIKey struct {
+	ID         string     `json:"id"` // the public part of the key, see auth.NewAPIKey
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  int        `json:"created_by"` // ID of the admin who created it
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // accurate to about a minute
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+
+	SecretHash string `json:"-"`
+}
+
+// Active reports whether the key can still be used at now
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && now.Before(k.ExpiresAt)
+}
+
+// HasScope reports whether the key was given scope
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+// NormalizeEmail checks that email is a plain address such as
+// ada@example.com and returns it trimmed, with the domain in lower case.
+// The local part is kept as typed, since mail servers may treat its case
+// as significant.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+
+	if email == "" {
+		return "", errors.New("is required")
+	}
+
+	addr, err := mail.ParseAddress(email)
+
+	// ParseAddress also accepts "Ada <ada@example.com>"; only the bare address is wanted
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return "", errors.New("is not a valid email address")
+	}
+
+	at := strings.LastIndex(email, "@")
+	local, domain := email[:at], strings.ToLower(email[at+1:])
+
+	if !validDomain(domain) {
+		return "", errors.New("must have a domain name such as example.com after the @")
+	}
+
+	return local + "@" + domain, nil
+}
+
+// EmailKey is the form under which emails are compared for uniqueness:
+// entirely in lower case, so Ada@example.com and ada@example.com can't
+// belong to two users
+func EmailKey(email string) string {
+	return strings.ToLower(email)
+}
+
+// validDomain accepts host names made of at least two labels of letters,
+// digits and inner hyphens
+func validDomain(domain string) bool {
+	labels := strings.Split(domain, ".")
+
+	if len(labels) < 2 || len(domain) > 253 {
+		return false
+	}
+
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for _, r := range label {
+			if !('a' <= r && r <= 'z' || '0' <= r && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -2,14 +2,43 @@ package models
 
 import "time"
 
+// Roles a user can have, from the most to the least powerful
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+	RoleMember  = "member"
+)
+
+var Roles = []string{RoleAdmin, RoleManager, RoleMember}
+
 type User struct {
 	ID        int       `json:"id"`
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
+	Version   int       `json:"version"` // 1 when created, raised by every update
+
+	PasswordHash string `json:"-"` // see auth.HashPassword; never sent to clients
+}
+
+// UserFields are the fields of a user that can be set when creating or
+// updating it. An empty Role means member for a new user and keeps the
+// role of an existing one.
+type UserFields struct {
+	Name  string
+	Email string
+	Role  string
 }
 
 type CreateUserRequest struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Role     string `json:"role,omitempty"`
+	Password string `json:"password,omitempty"` // only when creating a user
+}
+
+// Fields returns the user fields given by the request
+func (r CreateUserRequest) Fields() UserFields {
+	return UserFields{Name: r.Name, Email: r.Email, Role: r.Role}
 }
@@ -0,0 +1,202 @@
+// Package ratelimit limits how often each client may do something, with a
+// token bucket per client
+package ratelimit
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests requests per Per. A client may use them all at
+// once; after that they come back evenly, one every Per/Requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit reads a limit written as requests/period, e.g. 10/1m
+func ParseLimit(s string) (Limit, error) {
+	requests, per, ok := strings.Cut(s, "/")
+
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q is not requests/period, e.g. 10/1m", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("limit %q needs a positive number of requests", s)
+	}
+
+	d, err := time.ParseDuration(per)
+
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("limit %q needs a positive period such as 1m", s)
+	}
+
+	return Limit{Requests: n, Per: d}, nil
+}
+
+func (l Limit) String() string {
+	return strconv.Itoa(l.Requests) + "/" + l.Per.String()
+}
+
+// interval is how long one request takes to come back
+func (l Limit) interval() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+// Result is the state of a client's bucket after a request
+type Result struct {
+	Allowed    bool
+	Limit      Limit
+	Remaining  int           // requests the client can make right now
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed; 0 if it already is
+}
+
+// DefaultMaxClients is how many clients a Limiter keeps buckets for
+// unless SetMaxClients says otherwise
+const DefaultMaxClients = 100_000
+
+// Limiter keeps a token bucket for every client it sees. A bucket that has
+// been idle long enough to fill up is no different from a new one, so
+// those are dropped, keeping only the clients of the last period in
+// memory. Past the maximum number of clients, the least recently used
+// bucket makes way for a new one, so even many clients in one period,
+// such as a flood of addresses, can't grow memory without bound. It is
+// safe for concurrent use.
+type Limiter struct {
+	mu         sync.Mutex
+	limit      Limit
+	maxClients int
+	buckets    map[string]*list.Element // of *bucket
+	lru        *list.List               // the buckets, least recently used first
+	lastSweep  time.Time
+}
+
+// bucket holds tokens, one per request, as of the time it was last used
+type bucket struct {
+	client string
+	tokens float64
+	last   time.Time
+}
+
+func New(limit Limit) *Limiter {
+	if limit.Requests < 1 || limit.Per <= 0 {
+		panic("ratelimit: the limit needs positive Requests and Per")
+	}
+
+	return &Limiter{
+		limit:      limit,
+		maxClients: DefaultMaxClients,
+		buckets:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// SetMaxClients sets how many clients' buckets are kept at most, dropping
+// the least recently used ones beyond n. An evicted client starts again
+// with a full bucket, so n should be well above the clients of a period.
+func (l *Limiter) SetMaxClients(n int) {
+	if n < 1 {
+		panic("ratelimit: the maximum number of clients must be positive")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.maxClients = n
+
+	for len(l.buckets) > n {
+		l.evict(l.lru.Front())
+	}
+}
+
+// Allow takes a token from the client's bucket, if it has one
+func (l *Limiter) Allow(client string, now time.Time) Result {
+	return l.use(client, now, true)
+}
+
+// Peek reports what Allow would, without taking a token
+func (l *Limiter) Peek(client string, now time.Time) Result {
+	return l.use(client, now, false)
+}
+
+func (l *Limiter) use(client string, now time.Time, take bool) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= l.limit.Per {
+		l.sweep(now)
+	}
+
+	capacity := float64(l.limit.Requests)
+	perToken := l.limit.interval()
+	var b *bucket
+
+	if e, ok := l.buckets[client]; ok {
+		b = e.Value.(*bucket)
+		l.lru.MoveToBack(e)
+	} else {
+		b = &bucket{client: client, tokens: capacity, last: now}
+
+		// A new client's bucket is full; keep it only once it has been used
+		if take {
+			if len(l.buckets) >= l.maxClients {
+				l.evict(l.lru.Front())
+			}
+
+			l.buckets[client] = l.lru.PushBack(b)
+		}
+	}
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+float64(elapsed)/float64(perToken))
+		b.last = now
+	}
+
+	result := Result{Limit: l.limit}
+
+	if b.tokens >= 1 {
+		if take {
+			b.tokens--
+		}
+
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((capacity - b.tokens) * float64(perToken))
+
+	return result
+}
+
+// sweep drops the buckets that are full by now; the caller holds the lock
+func (l *Limiter) sweep(now time.Time) {
+	for e := l.lru.Front(); e != nil; {
+		next := e.Next()
+		b := e.Value.(*bucket)
+
+		if now.Sub(b.last) >= time.Duration((float64(l.limit.Requests)-b.tokens)*float64(l.limit.interval())) {
+			l.evict(e)
+		}
+
+		e = next
+	}
+
+	l.lastSweep = now
+}
+
+// evict drops the bucket in e; the caller holds the lock
+func (l *Limiter) evict(e *list.Element) {
+	delete(l.buckets, e.Value.(*bucket).client)
+	l.lru.Remove(e)
+}
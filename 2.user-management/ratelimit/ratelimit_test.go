@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	l := New(Limit{Requests: 2, Per: time.Minute})
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		after     time.Duration // since the previous request
+		allowed   bool
+		remaining int
+	}{
+		{0, true, 1},
+		{0, true, 0},
+		{time.Second, false, 0},
+		{29 * time.Second, true, 0}, // a token comes back every 30s
+		{30 * time.Second, true, 0},
+		{2 * time.Minute, true, 1}, // full again, never more
+	}
+
+	for i, tt := range tests {
+		now = now.Add(tt.after)
+		r := l.Allow("a", now)
+
+		if r.Allowed != tt.allowed || r.Remaining != tt.remaining {
+			t.Errorf("request %d: allowed %v with %d remaining, want %v with %d", i+1, r.Allowed, r.Remaining, tt.allowed, tt.remaining)
+		}
+	}
+}
+
+func TestPeek(t *testing.T) {
+	l := New(Limit{Requests: 1, Per: time.Minute})
+	now := time.Now()
+
+	if r := l.Peek("a", now); !r.Allowed || r.Remaining != 1 {
+		t.Errorf("Peek on a new client = %+v, want allowed with 1 remaining", r)
+	}
+
+	if len(l.buckets) != 0 {
+		t.Errorf("Peek kept %d buckets, want 0", len(l.buckets))
+	}
+
+	l.Allow("a", now)
+
+	if r := l.Peek("a", now); r.Allowed {
+		t.Error("Peek allowed a client with an empty bucket")
+	}
+
+	if r := l.Allow("a", now); r.Allowed {
+		t.Error("Peek gave a token back")
+	}
+}
+
+func TestMaxClients(t *testing.T) {
+	l := New(Limit{Requests: 1, Per: time.Hour})
+	l.SetMaxClients(3)
+	now := time.Now()
+
+	for _, c := range []string{"a", "b", "c"} {
+		l.Allow(c, now)
+	}
+
+	// Using a makes b the least recently used
+	if r := l.Allow("a", now); r.Allowed {
+		t.Fatal("a was allowed twice")
+	}
+
+	l.Allow("d", now)
+
+	if len(l.buckets) != 3 || l.lru.Len() != 3 {
+		t.Fatalf("%d buckets in the map and %d in the list, want 3", len(l.buckets), l.lru.Len())
+	}
+
+	if _, ok := l.buckets["b"]; ok {
+		t.Error("b was kept, though it was used least recently")
+	}
+
+	for _, c := range []string{"a", "c", "d"} {
+		if r := l.Allow(c, now); r.Allowed {
+			t.Errorf("%s got a new bucket", c)
+		}
+	}
+
+	// Many clients in one period never hold more than the maximum
+	for i := range 1000 {
+		l.Allow(strconv.Itoa(i), now)
+	}
+
+	if len(l.buckets) != 3 || l.lru.Len() != 3 {
+		t.Errorf("%d buckets in the map and %d in the list, want 3", len(l.buckets), l.lru.Len())
+	}
+
+	l.SetMaxClients(1)
+
+	if _, ok := l.buckets["999"]; len(l.buckets) != 1 || !ok {
+		t.Errorf("after SetMaxClients(1): %d buckets, want only the latest", len(l.buckets))
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l := New(Limit{Requests: 2, Per: time.Minute})
+	now := time.Now()
+
+	l.Allow("a", now)
+	l.Allow("b", now.Add(30*time.Second))
+	l.Allow("b", now.Add(30*time.Second))
+
+	// a is full again after 30s, b only a minute after its requests
+	l.Allow("c", now.Add(time.Minute-time.Second))
+
+	if len(l.buckets) != 3 {
+		t.Fatalf("%d buckets before a period has passed, want 3", len(l.buckets))
+	}
+
+	l.Allow("c", now.Add(time.Minute))
+
+	if _, ok := l.buckets["a"]; ok || len(l.buckets) != 2 || l.lru.Len() != 2 {
+		t.Errorf("after a sweep: %d buckets, want b and c", len(l.buckets))
+	}
+}
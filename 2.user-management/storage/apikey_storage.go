@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/neel07sanghvi/crud-api/models"
+)
+
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// APIKeyRepository stores API keys. Methods fail with ErrAPIKeyNotFound
+// when the key doesn't exist. Revoked keys are kept, so that the list
+// shows when they were revoked.
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	ListAPIKeys(ctx context.Context) ([]*models.APIKey, error)
+	GetAPIKey(ctx context.Context, id string) (*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string, at time.Time) error
+	TouchAPIKey(ctx context.Context, id string, at time.Time) error // records a use
+}
+
+// APIKeyStorage keeps API keys in memory and, if it has a path, writes
+// them all to that JSON file after every change. It is safe for
+// concurrent use.
+type APIKeyStorage struct {
+	mu   sync.RWMutex
+	keys map[string]*models.APIKey
+	path string
+}
+
+// fileAPIKey is a key as saved in the file, with its secret's hash
+type fileAPIKey struct {
+	*models.APIKey
+	SecretHash string `json:"secret_hash"`
+}
+
+func NewAPIKeyStorage() *APIKeyStorage {
+	return &APIKeyStorage{keys: make(map[string]*models.APIKey)}
+}
+
+// NewFileAPIKeyStorage loads the keys from path, starting empty if it doesn't exist yet
+func NewFileAPIKeyStorage(path string) (*APIKeyStorage, error) {
+	s := NewAPIKeyStorage()
+	s.path = path
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []fileAPIKey
+
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	for _, k := range keys {
+		if k.APIKey == nil {
+			return nil, fmt.Errorf("read %s: a key is null", path)
+		}
+
+		k.APIKey.SecretHash = k.SecretHash
+		s.keys[k.ID] = k.APIKey
+	}
+
+	return s, nil
+}
+
+func (s *APIKeyStorage) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.keys[key.ID]; exists {
+		return fmt.Errorf("%w: API key ID %q is taken", ErrConflict, key.ID)
+	}
+
+	s.keys[key.ID] = copyAPIKey(key)
+
+	if err := s.save(); err != nil {
+		delete(s.keys, key.ID)
+		return err
+	}
+
+	return nil
+}
+
+func (s *APIKeyStorage) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sorted(), nil
+}
+
+func (s *APIKeyStorage) GetAPIKey(ctx context.Context, id string) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, exists := s.keys[id]
+
+	if !exists {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	return copyAPIKey(key), nil
+}
+
+// RevokeAPIKey marks a key as revoked at the given time; revoking it again
+// keeps the first time
+func (s *APIKeyStorage) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	return s.modify(ctx, id, func(key *models.APIKey) {
+		if key.RevokedAt == nil {
+			key.RevokedAt = &at
+		}
+	})
+}
+
+func (s *APIKeyStorage) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	return s.modify(ctx, id, func(key *models.APIKey) {
+		key.LastUsedAt = &at
+	})
+}
+
+// modify applies change to a copy of a key and stores the copy, keeping
+// the old key if the file can't be written
+func (s *APIKeyStorage) modify(ctx context.Context, id string, change func(*models.APIKey)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, exists := s.keys[id]
+
+	if !exists {
+		return ErrAPIKeyNotFound
+	}
+
+	updated := copyAPIKey(old)
+	change(updated)
+	s.keys[id] = updated
+
+	if err := s.save(); err != nil {
+		s.keys[id] = old
+		return err
+	}
+
+	return nil
+}
+
+// sorted returns copies of the keys, oldest first; the caller holds the lock
+func (s *APIKeyStorage) sorted() []*models.APIKey {
+	keys := make([]*models.APIKey, 0, len(s.keys))
+
+	for _, key := range s.keys {
+		keys = append(keys, copyAPIKey(key))
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+
+		return keys[i].ID < keys[j].ID
+	})
+
+	return keys
+}
+
+// save writes the keys to the file, if there is one; the caller holds the write lock
+func (s *APIKeyStorage) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	var keys []fileAPIKey
+
+	for _, key := range s.sorted() {
+		keys = append(keys, fileAPIKey{APIKey: key, SecretHash: key.SecretHash})
+	}
+
+	data, err := json.MarshalIndent(keys, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("save API keys: %w", err)
+	}
+
+	return nil
+}
+
+func copyAPIKey(key *models.APIKey) *models.APIKey {
+	c := *key
+	c.Scopes = slices.Clone(key.Scopes)
+
+	if key.LastUsedAt != nil {
+		t := *key.LastUsedAt
+		c.LastUsedAt = &t
+	}
+
+	if key.RevokedAt != nil {
+		t := *key.RevokedAt
+		c.RevokedAt = &t
+	}
+
+	return &c
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/neel07sanghvi/crud-api/models"
+)
+
+// FileStorage keeps the users in memory like UserStorage and writes them
+// all to a JSON file after every change, so they survive a restart. It is
+// safe for concurrent use.
+type FileStorage struct {
+	mu     sync.Mutex // held while changing users, so the file is written in order
+	path   string
+	memory *UserStorage
+}
+
+type fileContents struct {
+	NextID int        `json:"next_id"`
+	Users  []fileUser `json:"users"`
+}
+
+// fileUser is a user as saved in the file, where unlike in responses the
+// password hash is kept
+type fileUser struct {
+	*models.User
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// NewFileStorage loads the users from path, starting empty if it doesn't exist yet
+func NewFileStorage(path string) (*FileStorage, error) {
+	s := &FileStorage{path: path, memory: NewEmpty()}
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var contents fileContents
+
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	for _, u := range contents.Users {
+		if u.User == nil {
+			return nil, fmt.Errorf("read %s: a user is null", path)
+		}
+
+		user := u.User
+		user.PasswordHash = u.PasswordHash
+
+		// Files written before users had versions and roles
+		if user.Version == 0 {
+			user.Version = 1
+		}
+
+		if user.Role == "" {
+			user.Role = models.RoleMember
+		}
+
+		s.memory.put(user)
+	}
+
+	s.memory.nextID = max(s.memory.nextID, contents.NextID)
+
+	return s, nil
+}
+
+func (s *FileStorage) CreateUser(ctx context.Context, fields models.UserFields, passwordHash string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, err := s.memory.CreateUser(ctx, fields, passwordHash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.save(); err != nil {
+		s.memory.remove(user.ID)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *FileStorage) GetAllUsers(ctx context.Context) ([]*models.User, error) {
+	return s.memory.GetAllUsers(ctx)
+}
+
+func (s *FileStorage) ListUsers(ctx context.Context, q UserQuery) (UserPage, error) {
+	return s.memory.ListUsers(ctx, q)
+}
+
+func (s *FileStorage) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	return s.memory.GetUserByID(ctx, id)
+}
+
+func (s *FileStorage) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.memory.GetUserByEmail(ctx, email)
+}
+
+func (s *FileStorage) UpdateUser(ctx context.Context, id, version int, fields models.UserFields) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := s.memory.GetUserByID(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.memory.UpdateUser(ctx, id, version, fields)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.save(); err != nil {
+		s.memory.put(old)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *FileStorage) SetPassword(ctx context.Context, id int, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := s.memory.GetUserByID(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	if err := s.memory.SetPassword(ctx, id, passwordHash); err != nil {
+		return err
+	}
+
+	if err := s.save(); err != nil {
+		s.memory.put(old)
+		return err
+	}
+
+	return nil
+}
+
+func (s *FileStorage) DeleteUser(ctx context.Context, id, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, err := s.memory.GetUserByID(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	if err := s.memory.DeleteUser(ctx, id, version); err != nil {
+		return err
+	}
+
+	if err := s.save(); err != nil {
+		s.memory.put(user)
+		return err
+	}
+
+	return nil
+}
+
+// save writes the file atomically: a crash leaves either the old or the new version
+func (s *FileStorage) save() error {
+	users, nextID := s.memory.snapshot()
+	contents := fileContents{NextID: nextID, Users: make([]fileUser, len(users))}
+
+	for i, user := range users {
+		contents.Users[i] = fileUser{User: user, PasswordHash: user.PasswordHash}
+	}
+
+	data, err := json.MarshalIndent(contents, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("save users: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data by writing a
+// temporary file next to it and renaming it over the old one
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,83 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/neel07sanghvi/crud-api/models"
+	"github.com/neel07sanghvi/crud-api/storage"
+	"github.com/neel07sanghvi/crud-api/storage/storagetest"
+)
+
+func TestFileStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.UserRepository {
+		s, err := storage.NewFileStorage(filepath.Join(t.TempDir(), "users.json"))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	})
+}
+
+// TestFileStorageConcurrentSaves checks that the file written under
+// concurrent changes holds exactly what the storage holds
+func TestFileStorageConcurrentSaves(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "users.json")
+	s, err := storage.NewFileStorage(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	for w := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 10 {
+				user, err := s.CreateUser(ctx, models.UserFields{Name: "user", Email: fmt.Sprintf("user%d-%d@example.com", w, i)}, "")
+
+				if err != nil {
+					t.Errorf("CreateUser: %v", err)
+					return
+				}
+
+				if i%2 == 0 {
+					if err := s.DeleteUser(ctx, user.ID, storage.AnyVersion); err != nil {
+						t.Errorf("DeleteUser(%d): %v", user.ID, err)
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	want, _ := s.GetAllUsers(ctx)
+	reopened, err := storage.NewFileStorage(path)
+
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+
+	got, _ := reopened.GetAllUsers(ctx)
+
+	if len(got) != len(want) || len(got) != 40 {
+		t.Fatalf("reopened storage has %d users, want %d (40)", len(got), len(want))
+	}
+
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Email != want[i].Email {
+			t.Errorf("user %d after reopening = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -1,77 +1,275 @@
 package storage
 
 import (
+	"context"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/neel07sanghvi/crud-api/models"
 )
 
+// UserStorage keeps users in memory. It is safe for concurrent use: reads
+// share a read lock, and every user it returns is a copy, so callers can't
+// change the stored users behind its back.
 type UserStorage struct {
+	mu     sync.RWMutex
 	users  map[int]*models.User
+	emails map[string]int // models.EmailKey of each email -> ID of its user
 	nextID int
 }
 
+// New returns an in-memory storage holding a couple of sample users
 func New() *UserStorage {
-	storage := &UserStorage{
+	storage := NewEmpty()
+
+	storage.CreateUser(context.Background(), models.UserFields{Name: "Hit Shiroya", Email: "hit@gmail.com"}, "")
+	storage.CreateUser(context.Background(), models.UserFields{Name: "Gautam Jivrajani", Email: "gautam@gmail.com"}, "")
+
+	return storage
+}
+
+func NewEmpty() *UserStorage {
+	return &UserStorage{
 		users:  make(map[int]*models.User),
+		emails: make(map[string]int),
 		nextID: 1,
 	}
+}
+
+func (s *UserStorage) CreateUser(ctx context.Context, fields models.UserFields, passwordHash string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	storage.CreateUser("Hit Shiroya", "hit@gmail.com")
-	storage.CreateUser("Gautam Jivrajani", "gautam@gmail.com")
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	return storage
-}
+	if err := s.checkEmail(0, fields.Email); err != nil {
+		return nil, err
+	}
+
+	if fields.Role == "" {
+		fields.Role = models.RoleMember
+	}
 
-func (s *UserStorage) CreateUser(name, email string) *models.User {
 	user := &models.User{
 		ID:        s.nextID,
-		Name:      name,
-		Email:     email,
+		Name:      fields.Name,
+		Email:     fields.Email,
+		Role:      fields.Role,
 		CreatedAt: time.Now(),
+		Version:   1,
+
+		PasswordHash: passwordHash,
 	}
 
 	s.users[s.nextID] = user
+	s.emails[models.EmailKey(fields.Email)] = user.ID
 	s.nextID++
 
-	return user
+	return copyUser(user), nil
 }
 
-func (s *UserStorage) GetAllUsers() []*models.User {
-	users := make([]*models.User, 0, len(s.users))
+func (s *UserStorage) GetAllUsers(ctx context.Context) ([]*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	for _, user := range s.users {
-		users = append(users, user)
+	users, _ := s.snapshot()
+
+	return users, nil
+}
+
+func (s *UserStorage) ListUsers(ctx context.Context, q UserQuery) (UserPage, error) {
+	if err := ctx.Err(); err != nil {
+		return UserPage{}, err
+	}
+
+	users, _ := s.snapshot()
+
+	return queryUsers(users, q)
+}
+
+func (s *UserStorage) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, exists := s.users[id]
+
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	return copyUser(user), nil
+}
+
+func (s *UserStorage) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, exists := s.emails[models.EmailKey(email)]
+
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	return copyUser(s.users[id]), nil
+}
+
+func (s *UserStorage) UpdateUser(ctx context.Context, id, version int, fields models.UserFields) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.users[id]
+
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	if version != AnyVersion && version != user.Version {
+		return nil, ErrVersionMismatch
 	}
 
-	return users
+	if err := s.checkEmail(id, fields.Email); err != nil {
+		return nil, err
+	}
+
+	// Replace rather than modify the stored user, so a copy being encoded
+	// elsewhere is never written to
+	updated := copyUser(user)
+	updated.Name = fields.Name
+	updated.Email = fields.Email
+	updated.Version++
+
+	if fields.Role != "" {
+		updated.Role = fields.Role
+	}
+
+	s.users[id] = updated
+	delete(s.emails, models.EmailKey(user.Email))
+	s.emails[models.EmailKey(fields.Email)] = id
+
+	return copyUser(updated), nil
 }
 
-func (s *UserStorage) GetUserByID(id int) (*models.User, bool) {
+// SetPassword replaces a user's password hash. It doesn't change the
+// version, since the hash isn't part of what clients see.
+func (s *UserStorage) SetPassword(ctx context.Context, id int, passwordHash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	user, exists := s.users[id]
 
-	return user, exists
+	if !exists {
+		return ErrNotFound
+	}
+
+	updated := copyUser(user)
+	updated.PasswordHash = passwordHash
+	s.users[id] = updated
+
+	return nil
 }
 
-func (s *UserStorage) UpdateUser(id int, name, email string) (*models.User, bool) {
+func (s *UserStorage) DeleteUser(ctx context.Context, id, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	user, exists := s.users[id]
 
 	if !exists {
-		return nil, false
+		return ErrNotFound
+	}
+
+	if version != AnyVersion && version != user.Version {
+		return ErrVersionMismatch
 	}
 
-	user.Name = name
-	user.Email = email
+	delete(s.users, id)
+	delete(s.emails, models.EmailKey(user.Email))
+
+	return nil
+}
+
+// checkEmail fails with a *ConflictError if email belongs to a user other
+// than id. Callers hold the write lock until the user is stored, so two
+// requests can't both claim the same email.
+func (s *UserStorage) checkEmail(id int, email string) error {
+	if owner, taken := s.emails[models.EmailKey(email)]; taken && owner != id {
+		return &ConflictError{Field: "email", Value: email}
+	}
 
-	return user, true
+	return nil
 }
 
-func (s *UserStorage) DeleteUser(id int) bool {
-	_, exists := s.users[id]
+// snapshot returns copies of all users ordered by ID, and the next ID to hand out
+func (s *UserStorage) snapshot() ([]*models.User, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*models.User, 0, len(s.users))
 
-	if exists {
-		delete(s.users, id)
+	for _, user := range s.users {
+		users = append(users, copyUser(user))
 	}
 
-	return exists
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	return users, s.nextID
+}
+
+// put stores user as it is, replacing any user with the same ID
+func (s *UserStorage) put(user *models.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, exists := s.users[user.ID]; exists {
+		delete(s.emails, models.EmailKey(old.Email))
+	}
+
+	s.users[user.ID] = copyUser(user)
+	s.emails[models.EmailKey(user.Email)] = user.ID
+	s.nextID = max(s.nextID, user.ID+1)
+}
+
+// remove deletes a user, also handing its ID out again if it was the last one
+func (s *UserStorage) remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, exists := s.users[id]; exists {
+		delete(s.emails, models.EmailKey(user.Email))
+	}
+
+	delete(s.users, id)
+
+	if id == s.nextID-1 {
+		s.nextID--
+	}
+}
+
+func copyUser(user *models.User) *models.User {
+	c := *user
+	return &c
 }
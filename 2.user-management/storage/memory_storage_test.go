@@ -0,0 +1,14 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/neel07sanghvi/crud-api/storage"
+	"github.com/neel07sanghvi/crud-api/storage/storagetest"
+)
+
+func TestUserStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.UserRepository {
+		return storage.NewEmpty()
+	})
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/neel07sanghvi/crud-api/models"
+)
+
+// ErrInvalidQuery is wrapped by errors about a UserQuery the caller got wrong
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Fields users can be sorted by
+var SortFields = []string{"id", "name", "email", "created_at"}
+
+type SortField struct {
+	Name string // one of SortFields
+	Desc bool
+}
+
+// UserQuery selects one page of users. Users are ordered by Sort and then
+// by ID, so the order is total and pages never overlap or skip a user.
+type UserQuery struct {
+	Email        string // exact match, ignoring case
+	NameContains string // substring, ignoring case
+	CreatedAfter time.Time
+	Sort         []SortField
+	Limit        int    // at most this many users; 0 means no limit
+	Cursor       string // from UserPage.NextCursor, to continue after the previous page
+}
+
+type UserPage struct {
+	Users      []*models.User
+	NextCursor string // empty on the last page
+}
+
+// cursor records where a page ended: the sort order and the last user's
+// sort keys, so the next page starts right after them even when users were
+// added or deleted in between
+type cursor struct {
+	Sort      string    `json:"sort"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FormatSort writes a sort order the way it is given in a URL, e.g. name,-created_at
+func FormatSort(fields []SortField) string {
+	parts := make([]string, len(fields))
+
+	for i, f := range fields {
+		parts[i] = f.Name
+
+		if f.Desc {
+			parts[i] = "-" + f.Name
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// queryUsers applies q to all users, which it may reorder
+func queryUsers(users []*models.User, q UserQuery) (UserPage, error) {
+	var after *models.User
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+
+		if err != nil {
+			return UserPage{}, err
+		}
+
+		if c.Sort != FormatSort(q.Sort) {
+			return UserPage{}, fmt.Errorf("%w: the cursor belongs to a listing sorted by %q", ErrInvalidQuery, c.Sort)
+		}
+
+		after = &models.User{ID: c.ID, Name: c.Name, Email: c.Email, CreatedAt: c.CreatedAt}
+	}
+
+	users = slices.DeleteFunc(users, func(u *models.User) bool {
+		return !q.matches(u) || (after != nil && compareUsers(u, after, q.Sort) <= 0)
+	})
+
+	slices.SortFunc(users, func(a, b *models.User) int {
+		return compareUsers(a, b, q.Sort)
+	})
+
+	page := UserPage{Users: users}
+
+	if q.Limit > 0 && len(users) > q.Limit {
+		page.Users = users[:q.Limit]
+		page.NextCursor = encodeCursor(page.Users[q.Limit-1], q.Sort)
+	}
+
+	return page, nil
+}
+
+func (q UserQuery) matches(u *models.User) bool {
+	if q.Email != "" && !strings.EqualFold(u.Email, q.Email) {
+		return false
+	}
+
+	if q.NameContains != "" && !strings.Contains(strings.ToLower(u.Name), strings.ToLower(q.NameContains)) {
+		return false
+	}
+
+	return q.CreatedAfter.IsZero() || u.CreatedAt.After(q.CreatedAfter)
+}
+
+// compareUsers orders users by the sort fields, then by ID
+func compareUsers(a, b *models.User, sort []SortField) int {
+	for _, f := range sort {
+		var c int
+
+		switch f.Name {
+		case "id":
+			c = cmp.Compare(a.ID, b.ID)
+		case "name":
+			c = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		case "email":
+			c = strings.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email))
+		case "created_at":
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		}
+
+		if f.Desc {
+			c = -c
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(a.ID, b.ID)
+}
+
+func encodeCursor(last *models.User, sort []SortField) string {
+	c := cursor{Sort: FormatSort(sort), ID: last.ID, CreatedAt: last.CreatedAt}
+
+	// Only keep the keys the order depends on, so the cursor stays short
+	for _, f := range sort {
+		switch f.Name {
+		case "name":
+			c.Name = last.Name
+		case "email":
+			c.Email = last.Email
+		}
+	}
+
+	data, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil || json.Unmarshal(data, &c) != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	return c, nil
+}
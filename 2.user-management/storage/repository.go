@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/neel07sanghvi/crud-api/models"
+)
+
+var (
+	ErrNotFound = errors.New("user not found")
+	ErrConflict = errors.New("user conflicts with an existing one")
+	// ErrVersionMismatch means the user was changed since the version the caller expected
+	ErrVersionMismatch = errors.New("user has been changed since that version")
+)
+
+// AnyVersion makes UpdateUser and DeleteUser skip the version check
+const AnyVersion = 0
+
+// ConflictError is the ErrConflict returned when a unique field of a user
+// already belongs to another user
+type ConflictError struct {
+	Field string
+	Value string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("a user with %s %q already exists", e.Field, e.Value)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// UserRepository stores users. Emails are unique regardless of case.
+// Methods fail with ErrNotFound when the user doesn't exist and a
+// *ConflictError when a change clashes with another user; any other error
+// means the storage itself failed. UpdateUser and DeleteUser fail with
+// ErrVersionMismatch unless version is the user's current version or
+// AnyVersion; the check is atomic with the change.
+//
+// Password hashes are stored as given ("" for a user who can't log in);
+// hashing is up to the caller.
+type UserRepository interface {
+	CreateUser(ctx context.Context, fields models.UserFields, passwordHash string) (*models.User, error)
+	GetAllUsers(ctx context.Context) ([]*models.User, error)
+	ListUsers(ctx context.Context, q UserQuery) (UserPage, error)
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdateUser(ctx context.Context, id, version int, fields models.UserFields) (*models.User, error)
+	SetPassword(ctx context.Context, id int, passwordHash string) error
+	DeleteUser(ctx context.Context, id, version int) error
+}
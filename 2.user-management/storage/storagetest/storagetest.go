@@ -0,0 +1,456 @@
+// Package storagetest checks that a storage.UserRepository behaves the way
+// the handlers expect. Each backend's tests call Run with a constructor for
+// an empty repository, in the spirit of testing/fstest.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/neel07sanghvi/crud-api/models"
+	"github.com/neel07sanghvi/crud-api/storage"
+)
+
+// Run runs the conformance tests against repositories made by newRepo,
+// which must return an empty repository each time it is called. Run the
+// tests with -race: one of them sends concurrent traffic.
+func Run(t *testing.T, newRepo func(t *testing.T) storage.UserRepository) {
+	ctx := context.Background()
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repo := newRepo(t)
+
+		created, err := repo.CreateUser(ctx, models.UserFields{Name: "Ada", Email: "ada@example.com"}, "")
+
+		if err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+
+		if created.ID == 0 || created.CreatedAt.IsZero() {
+			t.Errorf("CreateUser returned %+v, want an ID and creation time", created)
+		}
+
+		got, err := repo.GetUserByID(ctx, created.ID)
+
+		if err != nil {
+			t.Fatalf("GetUserByID(%d): %v", created.ID, err)
+		}
+
+		if got.Name != "Ada" || got.Email != "ada@example.com" || !got.CreatedAt.Equal(created.CreatedAt) {
+			t.Errorf("GetUserByID(%d) = %+v, want %+v", created.ID, got, created)
+		}
+	})
+
+	t.Run("UniqueIDs", func(t *testing.T) {
+		repo := newRepo(t)
+		seen := make(map[int]bool)
+
+		for i := range 5 {
+			user, err := repo.CreateUser(ctx, models.UserFields{Name: "Ada", Email: fmt.Sprintf("ada%d@example.com", i)}, "")
+
+			if err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+
+			if seen[user.ID] {
+				t.Fatalf("ID %d handed out twice", user.ID)
+			}
+
+			seen[user.ID] = true
+		}
+
+		users, err := repo.GetAllUsers(ctx)
+
+		if err != nil {
+			t.Fatalf("GetAllUsers: %v", err)
+		}
+
+		if len(users) != len(seen) {
+			t.Errorf("GetAllUsers returned %d users, want %d", len(users), len(seen))
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo(t)
+		created, _ := repo.CreateUser(ctx, models.UserFields{Name: "Ada", Email: "ada@example.com"}, "")
+
+		updated, err := repo.UpdateUser(ctx, created.ID, storage.AnyVersion, models.UserFields{Name: "Ada Lovelace", Email: "lovelace@example.com"})
+
+		if err != nil {
+			t.Fatalf("UpdateUser: %v", err)
+		}
+
+		if updated.ID != created.ID || updated.Name != "Ada Lovelace" || updated.Email != "lovelace@example.com" {
+			t.Errorf("UpdateUser returned %+v", updated)
+		}
+
+		got, _ := repo.GetUserByID(ctx, created.ID)
+
+		if got.Name != "Ada Lovelace" {
+			t.Errorf("after UpdateUser, GetUserByID returned %+v", got)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		created, _ := repo.CreateUser(ctx, models.UserFields{Name: "Ada", Email: "ada@example.com"}, "")
+
+		if err := repo.DeleteUser(ctx, created.ID, storage.AnyVersion); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+
+		if _, err := repo.GetUserByID(ctx, created.ID); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetUserByID after DeleteUser: got %v, want ErrNotFound", err)
+		}
+
+		if err := repo.DeleteUser(ctx, created.ID, storage.AnyVersion); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("second DeleteUser: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.GetUserByID(ctx, 42); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetUserByID: got %v, want ErrNotFound", err)
+		}
+
+		if _, err := repo.UpdateUser(ctx, 42, storage.AnyVersion, models.UserFields{Name: "Ada", Email: "ada@example.com"}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("UpdateUser: got %v, want ErrNotFound", err)
+		}
+
+		if err := repo.DeleteUser(ctx, 42, storage.AnyVersion); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("DeleteUser: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("CanceledContext", func(t *testing.T) {
+		repo := newRepo(t)
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		if _, err := repo.CreateUser(canceled, models.UserFields{Name: "Ada", Email: "ada@example.com"}, ""); !errors.Is(err, context.Canceled) {
+			t.Errorf("CreateUser: got %v, want context.Canceled", err)
+		}
+
+		if users, _ := repo.GetAllUsers(ctx); len(users) != 0 {
+			t.Errorf("a canceled CreateUser stored %d users", len(users))
+		}
+	})
+
+	t.Run("Versions", func(t *testing.T) {
+		repo := newRepo(t)
+		created, _ := repo.CreateUser(ctx, models.UserFields{Name: "Ada", Email: "ada@example.com"}, "")
+
+		if created.Version != 1 {
+			t.Errorf("CreateUser returned version %d, want 1", created.Version)
+		}
+
+		updated, err := repo.UpdateUser(ctx, created.ID, created.Version, models.UserFields{Name: "Ada Lovelace", Email: "ada@example.com"})
+
+		if err != nil {
+			t.Fatalf("UpdateUser with the current version: %v", err)
+		}
+
+		if updated.Version <= created.Version {
+			t.Errorf("UpdateUser returned version %d, want more than %d", updated.Version, created.Version)
+		}
+
+		if _, err := repo.UpdateUser(ctx, created.ID, created.Version, models.UserFields{Name: "Ada", Email: "ada@example.com"}); !errors.Is(err, storage.ErrVersionMismatch) {
+			t.Errorf("UpdateUser with a stale version: got %v, want ErrVersionMismatch", err)
+		}
+
+		if err := repo.DeleteUser(ctx, created.ID, created.Version); !errors.Is(err, storage.ErrVersionMismatch) {
+			t.Errorf("DeleteUser with a stale version: got %v, want ErrVersionMismatch", err)
+		}
+
+		if got, _ := repo.GetUserByID(ctx, created.ID); got == nil || got.Name != "Ada Lovelace" || got.Version != updated.Version {
+			t.Errorf("after the stale changes, GetUserByID returned %+v, want %+v", got, updated)
+		}
+
+		if err := repo.DeleteUser(ctx, created.ID, updated.Version); err != nil {
+			t.Errorf("DeleteUser with the current version: %v", err)
+		}
+
+		// Of many concurrent updates from the same version exactly one may win
+		user, _ := repo.CreateUser(ctx, models.UserFields{Name: "Bea", Email: "bea@example.com"}, "")
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		won := 0
+
+		for i := range 8 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, err := repo.UpdateUser(ctx, user.ID, user.Version, models.UserFields{Name: fmt.Sprintf("Bea %d", i), Email: "bea@example.com"})
+
+				if err == nil {
+					mu.Lock()
+					won++
+					mu.Unlock()
+				} else if !errors.Is(err, storage.ErrVersionMismatch) {
+					t.Errorf("UpdateUser: %v", err)
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		if won != 1 {
+			t.Errorf("%d concurrent updates from the same version succeeded, want 1", won)
+		}
+	})
+
+	t.Run("Roles", func(t *testing.T) {
+		repo := newRepo(t)
+		member, _ := repo.CreateUser(ctx, models.UserFields{Name: "Ada", Email: "ada@example.com"}, "")
+		manager, _ := repo.CreateUser(ctx, models.UserFields{Name: "Bea", Email: "bea@example.com", Role: models.RoleManager}, "")
+
+		if member.Role != models.RoleMember || manager.Role != models.RoleManager {
+			t.Errorf("CreateUser gave roles %q and %q, want %q and %q", member.Role, manager.Role, models.RoleMember, models.RoleManager)
+		}
+
+		updated, err := repo.UpdateUser(ctx, manager.ID, storage.AnyVersion, models.UserFields{Name: "Bea B", Email: "bea@example.com"})
+
+		if err != nil || updated.Role != models.RoleManager {
+			t.Errorf("UpdateUser without a role = %+v, %v; want the role kept", updated, err)
+		}
+
+		updated, err = repo.UpdateUser(ctx, member.ID, storage.AnyVersion, models.UserFields{Name: "Ada", Email: "ada@example.com", Role: models.RoleAdmin})
+
+		if err != nil || updated.Role != models.RoleAdmin {
+			t.Errorf("UpdateUser with a role = %+v, %v; want role %q", updated, err, models.RoleAdmin)
+		}
+	})
+
+	t.Run("Passwords", func(t *testing.T) {
+		repo := newRepo(t)
+		created, _ := repo.CreateUser(ctx, models.UserFields{Name: "Ada", Email: "ada@example.com"}, "hash-1")
+
+		got, err := repo.GetUserByEmail(ctx, "ADA@example.com")
+
+		if err != nil || got.ID != created.ID || got.PasswordHash != "hash-1" {
+			t.Fatalf("GetUserByEmail = %+v, %v; want user %d with its password hash", got, err, created.ID)
+		}
+
+		if err := repo.SetPassword(ctx, created.ID, "hash-2"); err != nil {
+			t.Fatalf("SetPassword: %v", err)
+		}
+
+		if got, _ := repo.GetUserByID(ctx, created.ID); got.PasswordHash != "hash-2" || got.Version != created.Version {
+			t.Errorf("after SetPassword, GetUserByID returned hash %q and version %d, want hash-2 and version %d", got.PasswordHash, got.Version, created.Version)
+		}
+
+		repo.UpdateUser(ctx, created.ID, storage.AnyVersion, models.UserFields{Name: "Ada", Email: "lovelace@example.com"})
+
+		if _, err := repo.GetUserByEmail(ctx, "ada@example.com"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetUserByEmail with a changed email: got %v, want ErrNotFound", err)
+		}
+
+		if got, err := repo.GetUserByEmail(ctx, "lovelace@example.com"); err != nil || got.ID != created.ID {
+			t.Errorf("GetUserByEmail with the new email = %+v, %v", got, err)
+		}
+
+		if err := repo.SetPassword(ctx, 42, "hash"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("SetPassword: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("UniqueEmail", func(t *testing.T) {
+		repo := newRepo(t)
+		ada, _ := repo.CreateUser(ctx, models.UserFields{Name: "Ada", Email: "ada@example.com"}, "")
+		bea, _ := repo.CreateUser(ctx, models.UserFields{Name: "Bea", Email: "bea@example.com"}, "")
+
+		_, err := repo.CreateUser(ctx, models.UserFields{Name: "Ada again", Email: "ADA@example.com"}, "")
+
+		var conflict *storage.ConflictError
+
+		if !errors.As(err, &conflict) || !errors.Is(err, storage.ErrConflict) || conflict.Field != "email" {
+			t.Errorf("CreateUser with a taken email: got %v, want a ConflictError on email", err)
+		}
+
+		if _, err := repo.UpdateUser(ctx, bea.ID, storage.AnyVersion, models.UserFields{Name: "Bea", Email: "ada@example.com"}); !errors.Is(err, storage.ErrConflict) {
+			t.Errorf("UpdateUser to a taken email: got %v, want ErrConflict", err)
+		}
+
+		if _, err := repo.UpdateUser(ctx, ada.ID, storage.AnyVersion, models.UserFields{Name: "Ada", Email: "Ada@example.com"}); err != nil {
+			t.Errorf("UpdateUser keeping its own email: %v", err)
+		}
+
+		if _, err := repo.UpdateUser(ctx, bea.ID, storage.AnyVersion, models.UserFields{Name: "Bea", Email: "bee@example.com"}); err != nil {
+			t.Fatalf("UpdateUser: %v", err)
+		}
+
+		if _, err := repo.CreateUser(ctx, models.UserFields{Name: "Bea again", Email: "bea@example.com"}, ""); err != nil {
+			t.Errorf("CreateUser with an email given up by UpdateUser: %v", err)
+		}
+
+		repo.DeleteUser(ctx, ada.ID, storage.AnyVersion)
+
+		if _, err := repo.CreateUser(ctx, models.UserFields{Name: "Ada again", Email: "ada@example.com"}, ""); err != nil {
+			t.Errorf("CreateUser with the email of a deleted user: %v", err)
+		}
+
+		// Of many concurrent creates with one email exactly one may win
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		created := 0
+
+		for range 8 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				if _, err := repo.CreateUser(ctx, models.UserFields{Name: "Cy", Email: "cy@example.com"}, ""); err == nil {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				} else if !errors.Is(err, storage.ErrConflict) {
+					t.Errorf("CreateUser: %v", err)
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		if created != 1 {
+			t.Errorf("%d concurrent creates with the same email succeeded, want 1", created)
+		}
+	})
+
+	t.Run("Pages", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i, name := range []string{"Dan", "ada", "Cy", "Bea", "ada", "Eve", "Cy"} {
+			if _, err := repo.CreateUser(ctx, models.UserFields{Name: name, Email: fmt.Sprintf("%s%d@example.com", name, i)}, ""); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+		}
+
+		sort := []storage.SortField{{Name: "name"}, {Name: "created_at", Desc: true}}
+		var names []string
+		seen := make(map[int]bool)
+		cursor := ""
+
+		for range 10 {
+			page, err := repo.ListUsers(ctx, storage.UserQuery{Sort: sort, Limit: 3, Cursor: cursor})
+
+			if err != nil {
+				t.Fatalf("ListUsers: %v", err)
+			}
+
+			for _, user := range page.Users {
+				if seen[user.ID] {
+					t.Fatalf("user %d is on two pages", user.ID)
+				}
+
+				seen[user.ID] = true
+				names = append(names, user.Name)
+			}
+
+			if cursor = page.NextCursor; cursor == "" {
+				break
+			}
+		}
+
+		if got, want := fmt.Sprint(names), "[ada ada Bea Cy Cy Dan Eve]"; got != want {
+			t.Errorf("pages hold %s, want %s", got, want)
+		}
+
+		page, err := repo.ListUsers(ctx, storage.UserQuery{NameContains: "A", Sort: sort})
+
+		if err != nil || len(page.Users) != 4 || page.NextCursor != "" {
+			t.Errorf("ListUsers(name_contains=A) = %d users, next %q, %v; want 4 users and no next page", len(page.Users), page.NextCursor, err)
+		}
+
+		if _, err := repo.ListUsers(ctx, storage.UserQuery{Cursor: "not a cursor"}); !errors.Is(err, storage.ErrInvalidQuery) {
+			t.Errorf("ListUsers with a bad cursor: got %v, want ErrInvalidQuery", err)
+		}
+	})
+
+	t.Run("ReturnsCopies", func(t *testing.T) {
+		repo := newRepo(t)
+		created, _ := repo.CreateUser(ctx, models.UserFields{Name: "Ada", Email: "ada@example.com"}, "")
+		created.Name = "changed by the caller"
+
+		got, _ := repo.GetUserByID(ctx, created.ID)
+
+		if got.Name != "Ada" {
+			t.Fatalf("changing the user returned by CreateUser changed the stored one to %q", got.Name)
+		}
+
+		got.Name = "changed by the caller"
+		users, _ := repo.GetAllUsers(ctx)
+
+		if users[0].Name != "Ada" {
+			t.Fatalf("changing the user returned by GetUserByID changed the stored one to %q", users[0].Name)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		repo := newRepo(t)
+		const workers, rounds = 8, 24
+
+		var wg sync.WaitGroup
+
+		for w := range workers {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for i := range rounds {
+					user, err := repo.CreateUser(ctx, models.UserFields{Name: fmt.Sprintf("user %d-%d", w, i), Email: fmt.Sprintf("user%d-%d@example.com", w, i)}, "")
+
+					if err != nil {
+						t.Errorf("CreateUser: %v", err)
+						return
+					}
+
+					if _, err := repo.UpdateUser(ctx, user.ID, storage.AnyVersion, models.UserFields{Name: "renamed", Email: fmt.Sprintf("renamed%d-%d@example.com", w, i)}); err != nil {
+						t.Errorf("UpdateUser(%d): %v", user.ID, err)
+					}
+
+					if _, err := repo.GetAllUsers(ctx); err != nil {
+						t.Errorf("GetAllUsers: %v", err)
+					}
+
+					// Delete every other user so deletes race with the other calls too
+					if i%2 == 0 {
+						if err := repo.DeleteUser(ctx, user.ID, storage.AnyVersion); err != nil {
+							t.Errorf("DeleteUser(%d): %v", user.ID, err)
+						}
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		users, err := repo.GetAllUsers(ctx)
+
+		if err != nil {
+			t.Fatalf("GetAllUsers: %v", err)
+		}
+
+		if want := workers * rounds / 2; len(users) != want {
+			t.Errorf("%d users left, want %d", len(users), want)
+		}
+
+		seen := make(map[int]bool)
+
+		for _, user := range users {
+			if seen[user.ID] {
+				t.Errorf("ID %d handed out twice", user.ID)
+			}
+
+			seen[user.ID] = true
+		}
+	})
+}